@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"epdcal/internal/ics"
+	appLog "epdcal/internal/log"
+)
+
+// lintSummary holds per-source counts for the lint-ics summary table.
+type lintSummary struct {
+	id        string
+	events    int
+	recurring int
+	overrides int
+	errors    int
+	warnings  int
+}
+
+// runLintICS implements `epdcal lint-ics [flags] <url|file>...`.
+//
+// It parses and expands each ICS payload with diagnostics enabled and prints
+// a summary table followed by every diagnostic. Nothing is cached or written;
+// local files are linted fully offline. The return value is the process exit
+// code: 0 when no errors were found, 1 on errors, 2 on usage problems.
+func runLintICS(args []string) int {
+	fs := flag.NewFlagSet("lint-ics", flag.ContinueOnError)
+	timezone := fs.String("timezone", "Asia/Seoul", "Display timezone used for floating/all-day values")
+	days := fs.Int("days", 35, "Number of future days to expand")
+	backfill := fs.Int("backfill", 7, "Number of past days to expand")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: epdcal lint-ics [flags] <url|file>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// Diagnostics are printed as a table; keep the regular log output quiet.
+	appLog.SetLevel(appLog.LevelError)
+
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid timezone %q: %v\n", *timezone, err)
+		return 2
+	}
+	now := time.Now().In(loc)
+	rangeStart := now.AddDate(0, 0, -*backfill)
+	rangeEnd := now.AddDate(0, 0, *days)
+
+	diags := &ics.Diagnostics{}
+	summaries := make([]lintSummary, 0, fs.NArg())
+
+	for _, target := range fs.Args() {
+		src := ics.Source{ID: lintSourceID(target), URL: target}
+		sum := lintSummary{id: src.ID}
+		before := diags.Items()
+
+		body, err := lintReadTarget(target)
+		if err != nil {
+			diags.Add(ics.Diagnostic{
+				SourceID: src.ID,
+				Severity: ics.SeverityError,
+				Message:  "read failed: " + err.Error(),
+			})
+		} else if events, perr := ics.ParseICS(src, body, ics.ParseConfig{
+			FloatingLocation: loc,
+			Diagnostics:      diags,
+		}); perr == nil {
+			sum.events = len(events)
+			for _, ev := range events {
+				if ev.IsOverride {
+					sum.overrides++
+				} else if ev.RawRRule != "" {
+					sum.recurring++
+				}
+			}
+			_, _ = ics.ExpandOccurrences(events, ics.ExpandConfig{
+				DisplayLocation: loc,
+				RangeStart:      rangeStart,
+				RangeEnd:        rangeEnd,
+				Diagnostics:     diags,
+			})
+		}
+
+		for _, d := range diags.Items()[len(before):] {
+			switch d.Severity {
+			case ics.SeverityError:
+				sum.errors++
+			case ics.SeverityWarning:
+				sum.warnings++
+			}
+		}
+		summaries = append(summaries, sum)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tEVENTS\tRECURRING\tOVERRIDES\tERRORS\tWARNINGS")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n", s.id, s.events, s.recurring, s.overrides, s.errors, s.warnings)
+	}
+	_ = tw.Flush()
+
+	items := diags.Items()
+	if len(items) > 0 {
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SEVERITY\tSOURCE\tLINE\tUID\tMESSAGE")
+		for _, d := range items {
+			line := "-"
+			if d.Line > 0 {
+				line = strconv.Itoa(d.Line)
+			}
+			uid := d.UID
+			if uid == "" {
+				uid = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.Severity, d.SourceID, line, uid, d.Message)
+		}
+		_ = tw.Flush()
+	}
+
+	if diags.Count(ics.SeverityError) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// lintReadTarget loads an ICS payload from an http(s) URL or a local file.
+func lintReadTarget(target string) ([]byte, error) {
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return os.ReadFile(target)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// lintSourceID derives a short display ID for a lint target. URLs are reduced
+// to their host so that private paths/tokens are not printed.
+func lintSourceID(target string) string {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		rest := target[strings.Index(target, "://")+3:]
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			rest = rest[:i]
+		}
+		return rest
+	}
+	return filepath.Base(target)
+}
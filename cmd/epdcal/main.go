@@ -1,16 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"flag"
+	"fmt"
 	"image"
 	"image/draw"
 	"image/png"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,10 +24,18 @@ import (
 
 	"epdcal/internal/capture"
 	"epdcal/internal/config"
+	"epdcal/internal/conflict"
 	"epdcal/internal/convert"
+	"epdcal/internal/dedup"
 	"epdcal/internal/epd"
+	"epdcal/internal/eventbus"
+	"epdcal/internal/holiday"
 	"epdcal/internal/ics"
 	appLog "epdcal/internal/log"
+	"epdcal/internal/metrics"
+	"epdcal/internal/model"
+	"epdcal/internal/rules"
+	"epdcal/internal/store"
 	"epdcal/internal/web"
 )
 
@@ -36,6 +50,16 @@ type flagConfig struct {
 }
 
 func main() {
+	// Subcommands run standalone and never start the daemon.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint-ics":
+			os.Exit(runLintICS(os.Args[2:]))
+		case "token":
+			os.Exit(runToken(os.Args[2:]))
+		}
+	}
+
 	appLog.Info("epdcal starting", "version", "0.0.1-dev")
 
 	// Parse CLI flags.
@@ -61,7 +85,8 @@ func main() {
 
 	appLog.Info("effective config",
 		"config_path", flags.configPath,
-		"listen", "http://"+conf.Listen,
+		"listen", conf.Listen,
+		"tls", conf.TLS.Enable,
 		"timezone", conf.Timezone,
 		"refresh_cron", conf.RefreshCron,
 		"horizon_days", conf.HorizonDays,
@@ -77,23 +102,11 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start HTTP server in background.
-	go func() {
-		if err := web.StartServer(ctx, conf, flags.debug); err != nil {
-			appLog.Error("http server failed", err)
-			cancel()
-		}
-	}()
+	// Shared event store: the refresh loop publishes, the web server reads.
+	st := store.New()
 
-	// Signal handling.
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		sig := <-sigCh
-		appLog.Info("signal received, shutting down", "signal", sig.String())
-		cancel()
-	}()
+	// Pipeline notifications for /api/stream subscribers.
+	bus := eventbus.New()
 
 	// Initialize EPD driver (C-based, via cgo) unless render-only.
 	// NOTE: This requires CGO_ENABLED=1 and the C driver (DEV_Config.c,
@@ -114,10 +127,86 @@ func main() {
 		}()
 	}
 
+	// captureURL is where the capture loads /calendar from: the listener
+	// itself, or its loopback plain HTTP twin when TLS is on. It is set
+	// once the server is up; captureReady is closed after that.
+	var captureURL string
+	captureReady := make(chan struct{})
+
+	// Pipeline operations for the admin API (/api/refresh, /api/render,
+	// /api/cache/clear). Render never touches the panel.
+	renderFlags := flags
+	renderFlags.renderOnly = true
+	actions := &web.Actions{
+		Refresh: func(ctx context.Context) error {
+			select {
+			case <-captureReady:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return runPipeline(ctx, conf, flags, epdDrv, st, bus, captureURL)
+		},
+		Render: func(ctx context.Context) error {
+			select {
+			case <-captureReady:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return runPipeline(ctx, conf, renderFlags, epdDrv, st, bus, captureURL)
+		},
+		ClearCaches: func() {
+			refreshSourceCache.Invalidate()
+			refreshExpandCache.Invalidate()
+		},
+		ConfigPath: flags.configPath,
+	}
+
+	// Start HTTP server in background. serverDone is closed once it has
+	// drained after ctx is cancelled (or failed).
+	serverReady := make(chan string, 1)
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		if err := web.StartServer(ctx, conf, flags.debug, st, bus, actions, serverReady); err != nil {
+			appLog.Error("http server failed", err)
+			cancel()
+		}
+	}()
+	defer func() {
+		// Let in-flight requests finish before the process exits.
+		cancel()
+		select {
+		case <-serverDone:
+		case <-time.After(web.ShutdownDrainTimeout + time.Second):
+			appLog.Info("timed out waiting for HTTP server shutdown")
+		}
+	}()
+
+	// Signal handling.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigCh
+		appLog.Info("signal received, shutting down", "signal", sig.String())
+		cancel()
+	}()
+
+	// The capture loads /calendar from our own server: wait for the
+	// listener instead of racing it.
+	select {
+	case captureURL = <-serverReady:
+		close(captureReady)
+	case <-ctx.Done():
+		appLog.Info("shutdown before HTTP server became ready; exiting")
+		return
+	}
+
 	// Scheduler / single-run behavior.
 	if flags.once {
 		appLog.Info("running in once mode (single refresh cycle)")
-		if err := runRefreshCycle(ctx, conf, flags.debug); err != nil {
+		gen, err := runRefreshCycle(ctx, conf, flags.debug, st, bus)
+		if err != nil {
 			appLog.Error("refresh cycle failed in once mode", err)
 			os.Exit(1)
 		}
@@ -126,7 +215,7 @@ func main() {
 		// preview.png를 생성하고, PNG → packed plane 변환 후 EPD에 출력한다.
 		// once 모드에서는 캡처/디스플레이 실패 시 프로세스를 종료하여
 		// 문제를 빠르게 드러내도록 한다.
-		if err := runCapturePipeline(ctx, conf, flags, epdDrv); err != nil {
+		if err := runCapturePipeline(ctx, conf, flags, epdDrv, gen, bus, captureURL); err != nil {
 			appLog.Error("capture/display pipeline failed in once mode", err)
 			os.Exit(1)
 		}
@@ -140,16 +229,18 @@ func main() {
 	)
 
 	// Initial immediate run.
-	if err := runRefreshCycle(ctx, conf, flags.debug); err != nil {
+	pipelineMu.Lock()
+	if gen, err := runRefreshCycle(ctx, conf, flags.debug, st, bus); err != nil {
 		appLog.Error("initial refresh cycle failed", err)
 	} else {
 		// 주기 루프에서도 매 refresh 이후에 /calendar를 Chromium으로 캡처하여
 		// preview.png를 최신 상태로 유지한다. 캡처 실패는 치명적이지 않으므로
 		// 에러만 로그에 남기고 루프는 계속 돈다.
-		if err := runCapturePipeline(ctx, conf, flags, epdDrv); err != nil {
+		if err := runCapturePipeline(ctx, conf, flags, epdDrv, gen, bus, captureURL); err != nil {
 			appLog.Error("chromium capture/display failed after initial refresh", err)
 		}
 	}
+	pipelineMu.Unlock()
 
 	// Use a cron-style scheduler for periodic refresh instead of a fixed ticker.
 	// This allows true "wall-clock aligned" schedules (e.g. */15 * * * *) and
@@ -173,11 +264,15 @@ func main() {
 		now := time.Now().In(loc)
 		appLog.Info("scheduled refresh tick (cron)", "time", now.Format(time.RFC3339))
 
-		if err := runRefreshCycle(ctx, conf, flags.debug); err != nil {
+		pipelineMu.Lock()
+		defer pipelineMu.Unlock()
+
+		gen, err := runRefreshCycle(ctx, conf, flags.debug, st, bus)
+		if err != nil {
 			appLog.Error("scheduled refresh cycle failed", err)
 			return
 		}
-		if err := runCapturePipeline(ctx, conf, flags, epdDrv); err != nil {
+		if err := runCapturePipeline(ctx, conf, flags, epdDrv, gen, bus, captureURL); err != nil {
 			appLog.Error("chromium capture/display failed after scheduled refresh", err)
 		}
 	})
@@ -213,16 +308,55 @@ func parseFlags() flagConfig {
 	return cfg
 }
 
-// runRefreshCycle performs a single ICS fetch+parse cycle for all configured
-// ICS sources. For now it only logs counts; later this will feed recurrence
-// expansion, rendering, and EPD display.
-func runRefreshCycle(parentCtx context.Context, conf *config.Config, debug bool) error {
+// refreshSourceCache keeps per-source parse/expand results across refresh
+// cycles, so feeds that did not change (304 / identical body) are not
+// re-parsed or re-expanded. refreshExpandCache does the same per UID for
+// feeds that did change.
+var (
+	refreshSourceCache = ics.NewSourceCache()
+	refreshExpandCache = ics.NewExpandCache(time.Hour)
+)
+
+// taskBackfillDays bounds how far back recurring task instances are expanded
+// so that recently missed instances still show up as overdue.
+const taskBackfillDays = 30
+
+// timeNow is the clock the refresh window is derived from (replaced in
+// tests to run cycles minutes apart).
+var timeNow = time.Now
+
+// pipelineMu serializes refresh + capture runs from the initial run, the
+// cron schedule and the admin API so they never share the capture output
+// or the panel.
+var pipelineMu sync.Mutex
+
+// runPipeline runs one refresh cycle followed by capture (and display,
+// unless flags.renderOnly) under pipelineMu.
+func runPipeline(ctx context.Context, conf *config.Config, flags flagConfig, drv *epd.CDriver, st *store.Store, bus *eventbus.Bus, captureURL string) error {
+	pipelineMu.Lock()
+	defer pipelineMu.Unlock()
+
+	gen, err := runRefreshCycle(ctx, conf, flags.debug, st, bus)
+	if err != nil {
+		return fmt.Errorf("refresh cycle: %w", err)
+	}
+	if err := runCapturePipeline(ctx, conf, flags, drv, gen, bus, captureURL); err != nil {
+		return fmt.Errorf("capture/display: %w", err)
+	}
+	return nil
+}
+
+// runRefreshCycle performs a single ICS fetch+parse+expand cycle for all
+// configured ICS sources and publishes the result to st. It returns the new
+// store generation (0 if nothing was published).
+func runRefreshCycle(parentCtx context.Context, conf *config.Config, debug bool, st *store.Store, bus *eventbus.Bus) (uint64, error) {
 	startTime := time.Now()
 	appLog.Info("refresh cycle start", "start_time", startTime.Format(time.RFC3339), "ics_count", len(conf.ICS), "debug", debug)
+	bus.Publish(eventbus.CycleStarted, map[string]any{"started_at": startTime})
 
 	if len(conf.ICS) == 0 {
-		appLog.Info("no ICS sources configured; skipping refresh cycle")
-		return nil
+		appLog.Info("no calendar sources configured; publishing empty snapshot")
+		return publishEmpty(st, bus), nil
 	}
 
 	// Derive a cycle-scoped context with timeout to avoid hanging forever
@@ -230,9 +364,15 @@ func runRefreshCycle(parentCtx context.Context, conf *config.Config, debug bool)
 	ctx, cancel := context.WithTimeout(parentCtx, 60*time.Second)
 	defer cancel()
 
-	// Build source list from config.
+	// Build source list from config. Built-in holiday sources are computed
+	// offline and kept apart from the ICS sources that need fetching.
 	sources := make([]ics.Source, 0, len(conf.ICS))
+	holidaySources := make([]config.ICSConfig, 0)
 	for _, csrc := range conf.ICS {
+		if csrc.IsHolidays() {
+			holidaySources = append(holidaySources, csrc)
+			continue
+		}
 		if csrc.URL == "" {
 			continue
 		}
@@ -251,9 +391,9 @@ func runRefreshCycle(parentCtx context.Context, conf *config.Config, debug bool)
 		})
 	}
 
-	if len(sources) == 0 {
-		appLog.Info("no valid ICS sources (all missing URLs); skipping refresh cycle")
-		return nil
+	if len(sources) == 0 && len(holidaySources) == 0 {
+		appLog.Info("no valid calendar sources (all missing URLs); publishing empty snapshot")
+		return publishEmpty(st, bus), nil
 	}
 
 	// cacheDir 선택:
@@ -266,39 +406,246 @@ func runRefreshCycle(parentCtx context.Context, conf *config.Config, debug bool)
 	}
 	fetcher := ics.NewFetcher(cacheDir)
 
-	// Fetch all ICS feeds.
-	fetchResults, fetchErrs := fetcher.FetchAll(ctx, sources)
-	if len(fetchErrs) > 0 {
-		appLog.Error("one or more ICS fetches failed", errorsAggregate(fetchErrs), "error_count", len(fetchErrs))
+	// Floating times and all-day dates are anchored in the display timezone
+	// rather than the host's system zone.
+	loc, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		appLog.Error("failed to load timezone, falling back to local", err, "timezone", conf.Timezone)
+		loc = time.Local
+	}
+	// Only keep events that can intersect the display horizon. The window
+	// covers the Web UI's five-week grid (view=week&weeks=5) as well as
+	// horizon_days, so nothing shown on the panel is dropped. It is
+	// aligned to local midnight so that the expansion caches hit on every
+	// refresh of the same day.
+	now := timeNow().In(loc)
+	rangeStart, rangeEnd := ics.DayWindow(now, 7, max(conf.HorizonDays, 35))
+	expandCfg := ics.ExpandConfig{
+		DisplayLocation:        loc,
+		RangeStart:             rangeStart,
+		RangeEnd:               rangeEnd,
+		MaxOccurrencesPerEvent: 5000,
+		Cache:                  refreshExpandCache,
 	}
 
-	totalParsedEvents := 0
+	snap := store.Snapshot{
+		RangeStart:   expandCfg.RangeStart,
+		RangeEnd:     expandCfg.RangeEnd,
+		Location:     loc,
+		Occurrences:  make([]model.Occurrence, 0),
+		SourceErrors: make(map[string]string),
+		Sources:      make(map[string]store.SourceStatus),
+	}
+	prev := st.Current()
+
+	// Rules (including highlight_red) are applied here, once per cycle, so
+	// that the API and the renderer see the same classification.
+	engine, err := rules.New(conf)
+	if err != nil {
+		appLog.Error("invalid event rules skipped", err)
+	}
 
-	for _, res := range fetchResults {
-		parsed, err := ics.ParseICS(res.Source, res.Body)
+	cachedSources := 0
+	fetchErrs := make([]error, 0)
+	parsedTasks := make([]ics.ParsedTask, 0)
+	carriedTasks := make([]model.Task, 0)
+
+	for _, src := range sources {
+		// Fetch and expand each source, collecting its diagnostics and
+		// VTODOs for /api/sources/{id}/diagnostics and /api/tasks. A failing
+		// source keeps its occurrences and tasks from the previous
+		// generation so that a flaky feed does not blank the panel.
+		diags := &ics.Diagnostics{}
+		srcCfg := expandCfg
+		srcCfg.Diagnostics = diags
+
+		res, err := fetcher.FetchOne(ctx, src)
+		var result ics.ExpandResult
+		hit := false
 		if err != nil {
-			appLog.Error("ics parse for source failed", err, "id", res.Source.ID, "url", icsRedactedURL(res.Source))
+			diags.Add(ics.Diagnostic{SourceID: src.ID, Severity: ics.SeverityError, Message: "fetch failed: " + err.Error()})
+		} else {
+			result, hit, err = refreshSourceCache.Expand(res, srcCfg)
+		}
+		if err != nil {
+			fetchErrs = append(fetchErrs, err)
+			appLog.Error("ics fetch/parse for source failed", err, "id", src.ID, "url", icsRedactedURL(src))
+			snap.SourceErrors[src.ID] = err.Error()
+			status := store.SourceStatus{FromCache: res.FromCache, Diagnostics: diags.Items()}
+			bus.Publish(eventbus.SourceFetched, sourceFetchedEvent{Source: src.ID, Error: err.Error()})
+			if prev != nil {
+				// 이전 cycle 의 분류 결과(ink, lane, conflict)가 아니라 규칙 적용 전
+				// occurrence 를 가져와 아래에서 현재 규칙/dedup/충돌 감지를 다시 거친다.
+				status.Expanded = prev.Sources[src.ID].Expanded
+				snap.Occurrences = append(snap.Occurrences, engine.Apply(status.Expanded)...)
+				for _, task := range prev.Tasks {
+					if task.SourceID == src.ID {
+						carriedTasks = append(carriedTasks, task)
+					}
+				}
+			}
+			status.Occurrences = len(status.Expanded)
+			snap.Sources[src.ID] = status
 			continue
 		}
-		totalParsedEvents += len(parsed)
+		if hit {
+			cachedSources++
+		}
+		if tasks, err := refreshSourceCache.Tasks(res, loc, diags); err != nil {
+			appLog.Error("ics task parse for source failed", err, "id", src.ID, "url", icsRedactedURL(src))
+		} else {
+			parsedTasks = append(parsedTasks, tasks...)
+		}
+		snap.Sources[src.ID] = store.SourceStatus{
+			FromCache:       res.FromCache,
+			ExpansionCached: hit,
+			Occurrences:     len(result.Occurrences),
+			Diagnostics:     diags.Items(),
+			Expanded:        result.Occurrences,
+		}
+		metrics.ExpandOccurrences.With(src.ID).Set(float64(len(result.Occurrences)))
+		metrics.ExpandTruncatedEvents.With(src.ID).Set(float64(len(result.TruncatedEvents)))
 
 		appLog.Info("ics source processed",
-			"id", res.Source.ID,
+			"id", src.ID,
 			"from_cache", res.FromCache,
-			"event_count", len(parsed),
+			"expansion_cached", hit,
+			"occurrence_count", len(result.Occurrences),
 		)
+		bus.Publish(eventbus.SourceFetched, sourceFetchedEvent{
+			Source:      src.ID,
+			FromCache:   res.FromCache,
+			Occurrences: len(result.Occurrences),
+		})
+
+		snap.Occurrences = append(snap.Occurrences, engine.Apply(result.Occurrences)...)
+		snap.TruncatedUIDs = append(snap.TruncatedUIDs, result.TruncatedEvents...)
+	}
+	if len(fetchErrs) > 0 {
+		appLog.Error("one or more ICS sources failed", errorsAggregate(fetchErrs), "error_count", len(fetchErrs))
+	}
+
+	// Tasks are expanded from taskBackfillDays ago so that recently missed
+	// instances still show up as overdue.
+	taskStart, _ := ics.DayWindow(now, taskBackfillDays, 0)
+	tasks, err := ics.ExpandTasks(parsedTasks, ics.ExpandConfig{
+		DisplayLocation:        loc,
+		RangeStart:             taskStart,
+		RangeEnd:               expandCfg.RangeEnd,
+		MaxOccurrencesPerEvent: expandCfg.MaxOccurrencesPerEvent,
+	})
+	if err != nil {
+		appLog.Error("task expansion failed", err)
+	}
+	snap.Tasks = append(tasks, carriedTasks...)
+	ics.SortTasks(snap.Tasks)
+
+	// Holiday sources never fail transiently: an unknown country is a
+	// configuration error and is reported on every cycle.
+	for _, hsrc := range holidaySources {
+		id := hsrc.SourceID()
+		country, ok := holiday.Lookup(hsrc.Country)
+		if !ok {
+			err := fmt.Errorf("unknown holiday country %q", hsrc.Country)
+			appLog.Error("holiday source skipped", err, "id", id)
+			snap.SourceErrors[id] = err.Error()
+			bus.Publish(eventbus.SourceFetched, sourceFetchedEvent{Source: id, Error: err.Error()})
+			continue
+		}
+		occs := country.Occurrences(id, loc, expandCfg.RangeStart, expandCfg.RangeEnd)
+		appLog.Info("holiday source processed", "id", id, "country", country.Code, "occurrence_count", len(occs))
+		bus.Publish(eventbus.SourceFetched, sourceFetchedEvent{Source: id, Occurrences: len(occs)})
+		snap.Occurrences = append(snap.Occurrences, engine.Apply(occs)...)
+	}
 
-		// TODO: store parsed events into a shared model/cache so that
-		// rendering/scheduling can consume them.
+	// Merge the same event reported by several calendars (e.g. a team
+	// calendar plus personal copies).
+	if !conf.Dedup.Disable {
+		before := len(snap.Occurrences)
+		precedence := append([]string{}, conf.Dedup.Precedence...)
+		for _, src := range sources {
+			precedence = append(precedence, src.ID)
+		}
+		for _, hsrc := range holidaySources {
+			precedence = append(precedence, hsrc.SourceID())
+		}
+		snap.Occurrences = dedup.Merge(snap.Occurrences, dedup.Options{
+			Precedence: precedence,
+			Fuzzy:      !conf.Dedup.DisableFuzzy,
+		})
+		if merged := before - len(snap.Occurrences); merged > 0 {
+			appLog.Info("merged duplicate occurrences across sources", "merged", merged)
+		}
 	}
 
+	// Double-booking detection needs the merged set of every source; rules
+	// that match on conflict (e.g. conflicts.highlight) run afterwards.
+	if !conf.Conflicts.Disable {
+		if n := conflict.Mark(snap.Occurrences, conflict.Options{Sources: conf.Conflicts.Sources}); n > 0 {
+			appLog.Info("conflicting occurrences detected", "count", n)
+		}
+	}
+	snap.Occurrences = engine.ApplyConflicts(snap.Occurrences)
+
+	sort.SliceStable(snap.Occurrences, func(i, j int) bool {
+		return snap.Occurrences[i].Start.Before(snap.Occurrences[j].Start)
+	})
+
+	gen := st.Publish(snap)
+
 	elapsed := time.Since(startTime)
+	outcome := "success"
+	switch {
+	case len(snap.SourceErrors) == 0:
+		metrics.RefreshLastSuccess.With().Set(float64(time.Now().Unix()))
+	case len(snap.SourceErrors) < len(sources)+len(holidaySources):
+		outcome = "partial"
+	default:
+		outcome = "failed"
+	}
+	metrics.RefreshCycles.With(outcome).Inc()
+	metrics.RefreshDuration.With().Observe(elapsed.Seconds())
 	appLog.Info("refresh cycle completed",
 		"duration", elapsed.String(),
-		"parsed_event_total", totalParsedEvents,
+		"generation", gen,
+		"occurrence_total", len(snap.Occurrences),
+		"cached_sources", cachedSources,
 	)
+	bus.Publish(eventbus.CycleCompleted, cycleCompletedEvent{
+		Generation:    gen,
+		Occurrences:   len(snap.Occurrences),
+		SourceErrors:  len(snap.SourceErrors),
+		CachedSources: cachedSources,
+		DurationMs:    elapsed.Milliseconds(),
+	})
 
-	return nil
+	return gen, nil
+}
+
+// sourceFetchedEvent is the payload of eventbus.SourceFetched.
+type sourceFetchedEvent struct {
+	Source      string `json:"source"`
+	FromCache   bool   `json:"from_cache"`
+	Occurrences int    `json:"occurrences"`
+	Error       string `json:"error,omitempty"`
+}
+
+// cycleCompletedEvent is the payload of eventbus.CycleCompleted.
+type cycleCompletedEvent struct {
+	Generation   uint64 `json:"generation"`
+	Occurrences  int    `json:"occurrences"`
+	SourceErrors int    `json:"source_errors"`
+	// CachedSources counts sources whose expansion came from
+	// refreshSourceCache (unchanged feed, same window).
+	CachedSources int   `json:"cached_sources"`
+	DurationMs    int64 `json:"duration_ms"`
+}
+
+// publishEmpty publishes an empty snapshot when no sources are configured.
+func publishEmpty(st *store.Store, bus *eventbus.Bus) uint64 {
+	gen := st.Publish(store.Snapshot{Occurrences: []model.Occurrence{}})
+	bus.Publish(eventbus.CycleCompleted, cycleCompletedEvent{Generation: gen})
+	return gen
 }
 
 // runCapturePipeline performs a Chromium-based PNG capture of the
@@ -309,14 +656,20 @@ func runRefreshCycle(parentCtx context.Context, conf *config.Config, debug bool)
 //   - also used in once mode to validate that the whole stack (web + capture)
 //     is working end-to-end.
 //
+// generation is the store generation published by the preceding refresh
+// cycle; the capture waits until the page reports that generation.
+//
+// baseURL is the server URL reported by web.StartServer (plain HTTP on
+// loopback when TLS is on, so the capture never deals with certificates).
+//
 // In debug mode it writes to ./cache/preview.png, otherwise to
 // /var/lib/epdcal/preview.png.
-func runCapturePipeline(parentCtx context.Context, conf *config.Config, flags flagConfig, drv *epd.CDriver) error {
+func runCapturePipeline(parentCtx context.Context, conf *config.Config, flags flagConfig, drv *epd.CDriver, generation uint64, bus *eventbus.Bus, baseURL string) error {
 	// Derive a short-lived context for the capture operation.
 	ctx, cancel := context.WithTimeout(parentCtx, 60*time.Second)
 	defer cancel()
 
-	url := "http://" + conf.Listen + "/calendar"
+	url := baseURL + "/calendar"
 
 	outPath := "/var/lib/epdcal/preview.png"
 	if flags.debug {
@@ -326,6 +679,7 @@ func runCapturePipeline(parentCtx context.Context, conf *config.Config, flags fl
 	appLog.Info("starting chromium capture",
 		"url", url,
 		"output", outPath,
+		"generation", generation,
 	)
 
 	opts := capture.CaptureOptions{
@@ -334,32 +688,42 @@ func runCapturePipeline(parentCtx context.Context, conf *config.Config, flags fl
 		Width:      0, // use defaults
 		Height:     0,
 		Timeout:    180 * time.Second,
+		Generation: generation,
+		// Identifies the capture to the server so that its requests are
+		// not rate limited (see web.CaptureSecret).
+		Headers: map[string]string{web.CaptureHeader: web.CaptureSecret()},
 	}
 	// If HTTP Basic Auth is configured, pass credentials through to the
 	// headless capture helper so that it can authenticate against the
-	// protected /calendar endpoint.
-	if conf.BasicAuth != nil &&
-		conf.BasicAuth.Username != "" &&
-		conf.BasicAuth.Password != "" {
-		opts.BasicAuthUsername = conf.BasicAuth.Username
-		opts.BasicAuthPassword = conf.BasicAuth.Password
-		appLog.Info("chromium capture using HTTP basic auth")
+	// protected /calendar endpoint. basic_auth comes first in Accounts();
+	// otherwise any user (a viewer is enough) will do.
+	if accounts := conf.Accounts(); len(accounts) > 0 {
+		opts.BasicAuthUsername = accounts[0].Username
+		opts.BasicAuthPassword = accounts[0].Password
+		appLog.Info("chromium capture using HTTP basic auth", "user", accounts[0].Username)
 	}
 
+	captureStart := time.Now()
 	if err := capture.CaptureCalendarPNG(ctx, opts); err != nil {
+		metrics.CaptureFailures.With().Inc()
 		return err
 	}
+	metrics.CaptureDuration.With().Observe(time.Since(captureStart).Seconds())
 
 	appLog.Info("chromium capture completed", "output", outPath)
 
 	// Load the captured PNG, convert to NRGBA, then pack into black/red planes.
-	f, err := os.Open(outPath)
+	pngData, err := os.ReadFile(outPath)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	sum := sha256.Sum256(pngData)
+	bus.Publish(eventbus.RenderCompleted, map[string]any{
+		"generation":   generation,
+		"preview_hash": hex.EncodeToString(sum[:8]),
+	})
 
-	img, err := png.Decode(f)
+	img, err := png.Decode(bytes.NewReader(pngData))
 	if err != nil {
 		return err
 	}
@@ -375,10 +739,12 @@ func runCapturePipeline(parentCtx context.Context, conf *config.Config, flags fl
 		nrgba = tmp
 	}
 
+	packStart := time.Now()
 	black, red, err := convert.PackNRGBA(nrgba, conf.Rotation)
 	if err != nil {
 		return err
 	}
+	metrics.PackDuration.With().Observe(time.Since(packStart).Seconds())
 
 	// If --dump is enabled, write black.bin and red.bin alongside preview.png.
 	if flags.dump {
@@ -404,9 +770,16 @@ func runCapturePipeline(parentCtx context.Context, conf *config.Config, flags fl
 	}
 
 	appLog.Info("sending frame to EPD hardware")
+	displayStart := time.Now()
 	if err := drv.Display(black, red); err != nil {
+		metrics.DisplayFailures.With().Inc()
 		return err
 	}
+	metrics.DisplayDuration.With().Observe(time.Since(displayStart).Seconds())
+	bus.Publish(eventbus.DisplayCompleted, map[string]any{
+		"generation":  generation,
+		"duration_ms": time.Since(displayStart).Milliseconds(),
+	})
 
 	appLog.Info("EPD frame update completed")
 	return nil
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"epdcal/internal/config"
+	"epdcal/internal/eventbus"
+	"epdcal/internal/model"
+	"epdcal/internal/store"
+)
+
+// TestRefreshCyclesMinutesApartHitSourceCache runs two refresh cycles five
+// minutes apart against an unchanged feed: the second one must be served
+// from refreshSourceCache.
+func TestRefreshCyclesMinutesApartHitSourceCache(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("..", "..", "internal", "ics", "testdata", "floating_time.ics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	// debug mode keeps the ICS cache under ./cache.
+	t.Chdir(t.TempDir())
+	refreshSourceCache.Invalidate()
+	refreshExpandCache.Invalidate()
+
+	loc, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Skip(err)
+	}
+	clock := time.Date(2025, 1, 8, 12, 0, 0, 0, loc)
+	timeNow = func() time.Time { return clock }
+	defer func() { timeNow = time.Now }()
+
+	conf := &config.Config{
+		Timezone:    "Asia/Seoul",
+		HorizonDays: 7,
+		ICS:         []config.ICSConfig{{ID: "work", URL: srv.URL + "/work.ics"}},
+	}
+	st := store.New()
+	bus := eventbus.New()
+	sub := bus.Subscribe(64)
+	defer sub.Close()
+
+	cycle := func() cycleCompletedEvent {
+		t.Helper()
+		if _, err := runRefreshCycle(context.Background(), conf, true, st, bus); err != nil {
+			t.Fatal(err)
+		}
+		for ev := range sub.C {
+			if ev.Type == eventbus.CycleCompleted {
+				return ev.Data.(cycleCompletedEvent)
+			}
+		}
+		t.Fatal("bus closed before cycle_completed")
+		return cycleCompletedEvent{}
+	}
+
+	first := cycle()
+	clock = clock.Add(5 * time.Minute)
+	second := cycle()
+
+	if first.CachedSources != 0 {
+		t.Errorf("first cycle: cached_sources = %d, want 0", first.CachedSources)
+	}
+	if second.CachedSources != 1 {
+		t.Errorf("second cycle five minutes later: cached_sources = %d, want 1", second.CachedSources)
+	}
+	if first.Occurrences == 0 || first.Occurrences != second.Occurrences {
+		t.Errorf("occurrences: first %d, second %d", first.Occurrences, second.Occurrences)
+	}
+}
+
+// TestFailedSourceIsReclassified turns conflicts.highlight off while a feed
+// is down: the occurrences carried over from the previous generation must
+// be classified again instead of keeping the previous cycle's red ink.
+func TestFailedSourceIsReclassified(t *testing.T) {
+	const feed = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//epdcal//test//EN\r\n" +
+		"BEGIN:VEVENT\r\nUID:a@epdcal\r\nDTSTART:20250108T100000\r\nDTEND:20250108T110000\r\nSUMMARY:A\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:b@epdcal\r\nDTSTART:20250108T103000\r\nDTEND:20250108T113000\r\nSUMMARY:B\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	down := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+
+	t.Chdir(t.TempDir())
+	refreshSourceCache.Invalidate()
+	refreshExpandCache.Invalidate()
+
+	loc, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Skip(err)
+	}
+	timeNow = func() time.Time { return time.Date(2025, 1, 8, 8, 0, 0, 0, loc) }
+	defer func() { timeNow = time.Now }()
+
+	conf := &config.Config{
+		Timezone:    "Asia/Seoul",
+		HorizonDays: 7,
+		ICS:         []config.ICSConfig{{ID: "work", URL: srv.URL + "/work.ics"}},
+		Conflicts:   config.ConflictConfig{Highlight: true},
+	}
+	st := store.New()
+	bus := eventbus.New()
+
+	if _, err := runRefreshCycle(context.Background(), conf, true, st, bus); err != nil {
+		t.Fatal(err)
+	}
+	for _, occ := range st.Current().Occurrences {
+		if occ.Ink != model.InkRed {
+			t.Fatalf("first cycle: %s ink %q, want red", occ.UID, occ.Ink)
+		}
+	}
+
+	// The feed goes down and there is no cached body to fall back to.
+	down = true
+	if err := os.RemoveAll("cache"); err != nil {
+		t.Fatal(err)
+	}
+	conf.Conflicts.Highlight = false
+	if _, err := runRefreshCycle(context.Background(), conf, true, st, bus); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := st.Current()
+	if snap.SourceErrors["work"] == "" {
+		t.Fatal("second cycle: expected a source error for work")
+	}
+	if len(snap.Occurrences) != 2 {
+		t.Fatalf("second cycle: %d occurrences carried over, want 2", len(snap.Occurrences))
+	}
+	for _, occ := range snap.Occurrences {
+		if occ.Ink != model.InkDefault || !occ.Conflict {
+			t.Errorf("second cycle: %s ink %q conflict %v, want default ink and conflict", occ.UID, occ.Ink, occ.Conflict)
+		}
+	}
+}
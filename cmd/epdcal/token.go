@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"epdcal/internal/apitoken"
+)
+
+// runToken implements `epdcal token create|list|revoke`.
+//
+// Tokens are stored hashed in the state file that the daemon reads
+// (apitoken.DefaultPath); the daemon picks up changes without a restart.
+// The return value is the process exit code: 0 on success, 1 on errors,
+// 2 on usage problems.
+func runToken(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: epdcal token <create|list|revoke> [flags]")
+		fmt.Fprintln(os.Stderr, "  create -name NAME -scopes read-events[,trigger-refresh][,admin]")
+		fmt.Fprintln(os.Stderr, "  list")
+		fmt.Fprintln(os.Stderr, "  revoke NAME|ID")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+
+	cmd := args[0]
+	fs := flag.NewFlagSet("token "+cmd, flag.ContinueOnError)
+	debug := fs.Bool("debug", false, "Use the debug state file (./cache/tokens.json)")
+	statePath := fs.String("state", "", "Token state file (default /var/lib/epdcal/tokens.json)")
+	name := fs.String("name", "", "Token name, logged on every use (create)")
+	scopes := fs.String("scopes", apitoken.ScopeReadEvents, "Comma-separated scopes: "+strings.Join(apitoken.Scopes, ", ")+" (create)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	path := *statePath
+	if path == "" {
+		path = apitoken.DefaultPath(*debug)
+	}
+	store := apitoken.Open(path)
+
+	switch cmd {
+	case "create":
+		secret, tok, err := store.Create(*name, apitoken.ParseScopes(*scopes))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "token create failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Created token %q (id %s, scopes %s).\n", tok.Name, tok.ID, strings.Join(tok.Scopes, ","))
+		fmt.Println("Use it as `Authorization: Bearer <token>`. It is not stored and cannot be shown again:")
+		fmt.Println()
+		fmt.Println(secret)
+		return 0
+
+	case "list":
+		tokens, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "token list failed: %v\n", err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSCOPES\tCREATED")
+		for _, t := range tokens {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.ID, t.Name, strings.Join(t.Scopes, ","), t.CreatedAt.Local().Format(time.DateTime))
+		}
+		_ = tw.Flush()
+		return 0
+
+	case "revoke":
+		if fs.NArg() != 1 {
+			usage()
+			return 2
+		}
+		tok, err := store.Revoke(fs.Arg(0))
+		if errors.Is(err, apitoken.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "no token named %q\n", fs.Arg(0))
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "token revoke failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Revoked token %q (id %s).\n", tok.Name, tok.ID)
+		return 0
+
+	default:
+		usage()
+		return 2
+	}
+}
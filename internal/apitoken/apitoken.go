@@ -0,0 +1,307 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Scopes a token can be granted.
+const (
+	// ScopeReadEvents allows the read-only API (/api/events, /api/stream,
+	// /calendar, /preview.png, ...), like a viewer account.
+	ScopeReadEvents = "read-events"
+	// ScopeTriggerRefresh allows POST /api/refresh and /api/render.
+	ScopeTriggerRefresh = "trigger-refresh"
+	// ScopeAdmin allows everything an admin account can do.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every valid scope.
+var Scopes = []string{ScopeReadEvents, ScopeTriggerRefresh, ScopeAdmin}
+
+// secretPrefix marks epdcal tokens so they are recognizable in scripts
+// and secret scanners.
+const secretPrefix = "epdcal_"
+
+// Errors returned by Store.
+var (
+	ErrNotFound  = errors.New("token not found")
+	ErrDuplicate = errors.New("a token with this name already exists")
+)
+
+// DefaultPath returns the token state file: prod vs debug, next to the
+// other runtime data.
+func DefaultPath(debug bool) string {
+	if debug {
+		return "./cache/tokens.json"
+	}
+	return "/var/lib/epdcal/tokens.json"
+}
+
+// Token is a stored API token. Only the SHA-256 of the secret is kept;
+// the secret itself is shown once by Create.
+type Token struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Has reports whether the token grants scope. ScopeAdmin grants every
+// scope.
+func (t Token) Has(scope string) bool {
+	return slices.Contains(t.Scopes, ScopeAdmin) || slices.Contains(t.Scopes, scope)
+}
+
+// stateFile is the on-disk JSON shape.
+type stateFile struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// Store reads and writes the token state file. The file is re-read when
+// its size or mtime changes, so tokens created or revoked with
+// `epdcal token` take effect in a running daemon without a restart.
+// It is safe for concurrent use.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	loaded  bool
+	tokens  []Token
+}
+
+// Open returns a Store for path. The file is read lazily; a missing file
+// is an empty store.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the state file path.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// List returns the stored tokens ordered by creation.
+func (s *Store) List() ([]Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.tokens), nil
+}
+
+// Lookup returns the token whose secret is secret. Every stored hash is
+// compared in constant time.
+func (s *Store) Lookup(secret string) (Token, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return Token{}, false, err
+	}
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return Token{}, false, nil
+	}
+	hash := hashSecret(secret)
+
+	var found Token
+	ok := false
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(t.Hash)) == 1 && !ok {
+			found, ok = t, true
+		}
+	}
+	return found, ok, nil
+}
+
+// Create stores a new token and returns its secret, which cannot be
+// recovered later.
+func (s *Store) Create(name string, scopes []string) (string, Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", Token{}, errors.New("token name is required")
+	}
+	if len(scopes) == 0 {
+		return "", Token{}, errors.New("at least one scope is required")
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(Scopes, sc) {
+			return "", Token{}, fmt.Errorf("unknown scope %q (valid: %s)", sc, strings.Join(Scopes, ", "))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return "", Token{}, err
+	}
+	for _, t := range s.tokens {
+		if t.Name == name {
+			return "", Token{}, ErrDuplicate
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", Token{}, err
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", Token{}, err
+	}
+	secret := secretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	tok := Token{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Scopes:    slices.Clone(scopes),
+		Hash:      hashSecret(secret),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	next := append(slices.Clone(s.tokens), tok)
+	if err := s.saveLocked(next); err != nil {
+		return "", Token{}, err
+	}
+	return secret, tok, nil
+}
+
+// Revoke deletes the token with the given name or ID.
+func (s *Store) Revoke(nameOrID string) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return Token{}, err
+	}
+	i := slices.IndexFunc(s.tokens, func(t Token) bool {
+		return t.Name == nameOrID || t.ID == nameOrID
+	})
+	if i < 0 {
+		return Token{}, ErrNotFound
+	}
+	revoked := s.tokens[i]
+	next := slices.Delete(slices.Clone(s.tokens), i, i+1)
+	if err := s.saveLocked(next); err != nil {
+		return Token{}, err
+	}
+	return revoked, nil
+}
+
+// ParseScopes splits a comma-separated scope list.
+func ParseScopes(csv string) []string {
+	var out []string
+	for _, sc := range strings.Split(csv, ",") {
+		sc = strings.ToLower(strings.TrimSpace(sc))
+		if sc != "" && !slices.Contains(out, sc) {
+			out = append(out, sc)
+		}
+	}
+	return out
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// reloadLocked re-reads the state file if it changed since the last read.
+func (s *Store) reloadLocked() error {
+	fi, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.tokens, s.loaded = nil, true
+		s.modTime, s.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.loaded && fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var st stateFile
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	s.tokens, s.loaded = st.Tokens, true
+	s.modTime, s.size = fi.ModTime(), fi.Size()
+	return nil
+}
+
+// saveLocked writes tokens atomically with 0600 permissions. The file
+// keeps the owner of the file it replaces (or of its directory), so that
+// running `epdcal token` as root does not lock out the service user.
+func (s *Store) saveLocked(tokens []Token) error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(stateFile{Tokens: tokens}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".epdcal-tokens-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0o600); err != nil {
+		return err
+	}
+	if owner, err := os.Stat(s.path); err == nil {
+		chownLike(tmpName, owner)
+	} else if owner, err := os.Stat(dir); err == nil {
+		chownLike(tmpName, owner)
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		return err
+	}
+
+	s.tokens = tokens
+	s.loaded = false // pick up the new mtime on the next read
+	return nil
+}
+
+// chownLike gives path the owner of fi, best effort (only root can).
+func chownLike(path string, fi os.FileInfo) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && os.Geteuid() == 0 {
+		_ = os.Chown(path, int(st.Uid), int(st.Gid))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 )
 
@@ -43,6 +44,15 @@ type CaptureOptions struct {
 	// same BasicAuth protection as normal clients.
 	BasicAuthUsername string
 	BasicAuthPassword string
+
+	// Headers are added to every request the page makes (e.g. the
+	// server's capture secret, which exempts it from rate limits).
+	Headers map[string]string
+
+	// Generation, if non-zero, additionally requires the page to report
+	// data-generation="<Generation>", i.e. to render data from that exact
+	// store generation (see /api/events "generation").
+	Generation uint64
 }
 
 // CaptureCalendarPNG launches (or attaches to) a headless Chromium instance
@@ -55,6 +65,9 @@ type CaptureOptions struct {
 //     <div data-ready="true" ...>
 //   - This function will wait until `[data-ready="true"]` is visible before
 //     taking the screenshot.
+//   - If opts.Generation is set, the same element must also carry
+//     data-generation="<Generation>", so a page that loaded data from an
+//     older refresh cycle is not captured.
 //
 // Note: This helper does NOT perform any NRGBA -> packed 1bpp conversion;
 // that is left to the caller. The resulting PNG is a full-color screenshot.
@@ -109,15 +122,29 @@ func CaptureCalendarPNG(parentCtx context.Context, opts CaptureOptions) error {
 		targetURL = u.String()
 	}
 
+	readySelector := `[data-ready="true"]`
+	if opts.Generation > 0 {
+		readySelector += fmt.Sprintf(`[data-generation="%d"]`, opts.Generation)
+	}
+
 	tasks := chromedp.Tasks{
 		chromedp.EmulateViewport(int64(opts.Width), int64(opts.Height)),
+	}
+	if len(opts.Headers) > 0 {
+		headers := make(network.Headers, len(opts.Headers))
+		for k, v := range opts.Headers {
+			headers[k] = v
+		}
+		tasks = append(tasks, network.Enable(), network.SetExtraHTTPHeaders(headers))
+	}
+	tasks = append(tasks,
 		chromedp.Navigate(targetURL),
 		// Wait until /calendar signals that it has finished loading data
 		// and rendering via data-ready="true".
-		chromedp.WaitVisible(`[data-ready="true"]`, chromedp.ByQuery),
-		chromedp.Sleep(500 * time.Millisecond),
+		chromedp.WaitVisible(readySelector, chromedp.ByQuery),
+		chromedp.Sleep(500*time.Millisecond),
 		chromedp.FullScreenshot(&png, 100),
-	}
+	)
 
 	if err := chromedp.Run(ctx, tasks); err != nil {
 		return fmt.Errorf("capture: chromedp run failed: %w", err)
@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -21,6 +22,204 @@ type ICSConfig struct {
 	ID string `yaml:"id" json:"id"`
 	// Name is a human-friendly label shown in the UI.
 	Name string `yaml:"name" json:"name"`
+
+	// Type selects the source kind: "" or "ics" for an ICS subscription,
+	// "holidays" for the built-in offline public holiday calendar of Country
+	// (URL is ignored).
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Country is the ISO 3166-1 alpha-2 code for Type "holidays" (e.g. "KR").
+	Country string `yaml:"country,omitempty" json:"country,omitempty"`
+}
+
+// IsHolidays reports whether the source is a built-in holiday calendar.
+func (c ICSConfig) IsHolidays() bool {
+	return strings.EqualFold(strings.TrimSpace(c.Type), "holidays")
+}
+
+// SourceID returns the ID used for occurrences of this source: ID, else
+// Name, else URL (or "holidays-<country>" for holiday sources).
+func (c ICSConfig) SourceID() string {
+	switch {
+	case c.ID != "":
+		return c.ID
+	case c.Name != "":
+		return c.Name
+	case c.IsHolidays():
+		return "holidays-" + strings.ToLower(c.Country)
+	default:
+		return c.URL
+	}
+}
+
+// RuleConfig is a single event rule applied after recurrence expansion.
+// All non-empty Match fields must match (AND); an empty Match matches every
+// occurrence. Rules run in order and later actions override earlier ones,
+// except that rules matching on Conflict form a second phase (see
+// Conflict).
+type RuleConfig struct {
+	// Name is used in logs and error messages only.
+	Name   string     `yaml:"name,omitempty" json:"name,omitempty"`
+	Match  RuleMatch  `yaml:"match" json:"match"`
+	Action RuleAction `yaml:"action" json:"action"`
+}
+
+// RuleMatch selects the occurrences a rule applies to.
+type RuleMatch struct {
+	// Sources limits the rule to these ICS source IDs.
+	Sources []string `yaml:"sources,omitempty" json:"sources,omitempty"`
+	// Summary / Location are Go regular expressions (RE2). Use "(?i)" for
+	// case-insensitive matching.
+	Summary  string `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+	// Categories matches if the event has any of these CATEGORIES
+	// (case-insensitive).
+	Categories []string `yaml:"categories,omitempty" json:"categories,omitempty"`
+	// Keywords matches if the summary or description contains any of these
+	// strings (case-insensitive). highlight_red is expressed this way.
+	Keywords []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+	// AllDay, if set, matches only all-day (true) or timed (false) events.
+	AllDay *bool `yaml:"all_day,omitempty" json:"all_day,omitempty"`
+	// Weekdays matches the start weekday in the display timezone
+	// ("mon".."sun" or full English names).
+	Weekdays []string `yaml:"weekdays,omitempty" json:"weekdays,omitempty"`
+	// Conflict, if set, matches only occurrences that do (true) or do not
+	// (false) clash with another busy event. Rules using it run after
+	// conflict detection, i.e. after all other rules and dedup, so they
+	// override those rules whatever their position in the list.
+	Conflict *bool `yaml:"conflict,omitempty" json:"conflict,omitempty"`
+}
+
+// RuleAction describes what happens to a matched occurrence.
+type RuleAction struct {
+	// Hide drops the occurrence entirely.
+	Hide bool `yaml:"hide,omitempty" json:"hide,omitempty"`
+	// Rename replaces the summary. If Match.Summary is set it is used as a
+	// regexp replacement template ($1 etc.), so `summary: '^\[EXTERNAL\]\s*'`
+	// with `rename: ""` strips the prefix; otherwise it is the new summary.
+	Rename *string `yaml:"rename,omitempty" json:"rename,omitempty"`
+	// MaxLength shortens the summary to this many characters (with "…").
+	MaxLength int `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	// AllDayLane shows a timed event in the all-day lane.
+	AllDayLane bool `yaml:"all_day_lane,omitempty" json:"all_day_lane,omitempty"`
+	// Ink sets the panel ink color: "black" or "red".
+	Ink string `yaml:"ink,omitempty" json:"ink,omitempty"`
+}
+
+// DedupConfig controls cross-source duplicate merging. The zero value
+// enables both UID and fuzzy (summary+start+end) merging with the ICS list
+// order as precedence.
+type DedupConfig struct {
+	// Disable turns off duplicate merging entirely.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+	// DisableFuzzy keeps UID+start merging but turns off summary-based
+	// merging for events whose UIDs differ.
+	DisableFuzzy bool `yaml:"disable_fuzzy,omitempty" json:"disable_fuzzy,omitempty"`
+	// Precedence lists source IDs whose copy wins, most preferred first.
+	// Unlisted sources follow in ICS list order.
+	Precedence []string `yaml:"precedence,omitempty" json:"precedence,omitempty"`
+}
+
+// ConflictConfig controls double-booking detection. The zero value checks
+// every source and only flags conflicts (no ink change).
+type ConflictConfig struct {
+	// Disable turns off conflict detection entirely.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+	// Sources lists the source IDs whose events block time. Empty means
+	// every source. Transparent and all-day events never block time.
+	Sources []string `yaml:"sources,omitempty" json:"sources,omitempty"`
+	// Highlight draws conflicting occurrences in red. It is evaluated as a
+	// rule matching `conflict: true` ahead of user rules that use conflict.
+	Highlight bool `yaml:"highlight,omitempty" json:"highlight,omitempty"`
+}
+
+// MetricsConfig controls the Prometheus /metrics endpoint. The zero value
+// serves metrics behind the same authentication as the rest of the API.
+type MetricsConfig struct {
+	// Disable removes the /metrics endpoint.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+	// Public serves /metrics without any authentication (e.g. for a scraper
+	// on a trusted LAN while the UI stays behind basic_auth).
+	Public bool `yaml:"public,omitempty" json:"public,omitempty"`
+	// BearerToken, if set, is required as `Authorization: Bearer <token>`
+	// instead of basic_auth, so the scraper never holds UI credentials.
+	BearerToken string `yaml:"bearer_token,omitempty" json:"bearer_token,omitempty"`
+}
+
+// ExportConfig controls the merged /calendar.ics feed.
+type ExportConfig struct {
+	// Disable removes /calendar.ics and the token URLs.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+	// Name is the calendar name shown by subscribing apps (default "epdcal").
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Tokens enable /export/<token>/calendar.ics, which needs no basic_auth
+	// so that phones can subscribe. Each token gets its own URL.
+	Tokens []ExportToken `yaml:"tokens,omitempty" json:"tokens,omitempty"`
+}
+
+// ExportToken is a secret path segment for a subscribable export URL.
+type ExportToken struct {
+	// Name identifies the subscriber in logs (e.g. "phone").
+	Name  string `yaml:"name" json:"name"`
+	Token string `yaml:"token" json:"token"`
+}
+
+// RateLimitConfig controls brute-force protection and per-IP rate limits.
+// Zero values use the defaults in parentheses. The chromium capture (a
+// loopback client presenting the per-process capture secret) is never
+// limited; other loopback clients, e.g. a reverse proxy on the same host,
+// are.
+type RateLimitConfig struct {
+	// Disable turns off both the auth lockout and the rate limits.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+	// MaxAuthFailures is the number of failed logins from one IP before it
+	// is locked out (5).
+	MaxAuthFailures int `yaml:"max_auth_failures,omitempty" json:"max_auth_failures,omitempty"`
+	// LockoutSeconds is the first lockout (60). Each further lockout of the
+	// same IP doubles it, up to MaxLockoutSeconds (3600).
+	LockoutSeconds    int `yaml:"lockout_seconds,omitempty" json:"lockout_seconds,omitempty"`
+	MaxLockoutSeconds int `yaml:"max_lockout_seconds,omitempty" json:"max_lockout_seconds,omitempty"`
+	// RequestsPerMinute and Burst size the per-IP token bucket on expensive
+	// endpoints (/api/events, /api/tasks, /calendar.ics, refresh/render,
+	// ...) (30 per minute, burst 10).
+	RequestsPerMinute int `yaml:"requests_per_minute,omitempty" json:"requests_per_minute,omitempty"`
+	Burst             int `yaml:"burst,omitempty" json:"burst,omitempty"`
+}
+
+// TLSConfig enables HTTPS for the Web UI/API.
+type TLSConfig struct {
+	// Enable serves Listen over HTTPS.
+	Enable bool `yaml:"enable,omitempty" json:"enable,omitempty"`
+	// CertFile and KeyFile are PEM files (e.g. from certbot). They are
+	// re-read when they change. If both are empty a self-signed
+	// certificate is generated under /var/lib/epdcal/tls and reused.
+	CertFile string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+	// Hosts are extra DNS names / IPs for the self-signed certificate
+	// (localhost, the hostname and local IPs are always included).
+	Hosts []string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	// RedirectListen, if set (e.g. ":80"), serves plain HTTP that only
+	// redirects to HTTPS.
+	RedirectListen string `yaml:"redirect_listen,omitempty" json:"redirect_listen,omitempty"`
+	// CaptureListen is the loopback-only plain HTTP listener the chromium
+	// capture uses while TLS is on (default "127.0.0.1:0", a free port).
+	CaptureListen string `yaml:"capture_listen,omitempty" json:"capture_listen,omitempty"`
+}
+
+// Roles for UserConfig.Role.
+const (
+	// RoleViewer may read the calendar, events, preview and other GET APIs.
+	RoleViewer = "viewer"
+	// RoleAdmin may additionally change config, trigger refresh/render and
+	// clear caches.
+	RoleAdmin = "admin"
+)
+
+// UserConfig is an HTTP Basic Auth account with a role.
+type UserConfig struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	// Role is "viewer" (default) or "admin".
+	Role string `yaml:"role,omitempty" json:"role,omitempty"`
 }
 
 // BasicAuthConfig holds HTTP Basic Auth credentials for the Web UI/API.
@@ -67,15 +266,66 @@ type Config struct {
 	// ShowAllDay toggles the all-day section in the rendered view.
 	ShowAllDay bool `yaml:"show_all_day" json:"show_all_day"`
 
+	// MaxEventsPerDay caps the events drawn per day in the server-side
+	// layout; the rest are summarized as "N more". 0 means unlimited.
+	MaxEventsPerDay int `yaml:"max_events_per_day,omitempty" json:"max_events_per_day,omitempty"`
+
 	// HighlightRed is a list of keywords that cause events to be rendered in red.
+	// It is evaluated as a keyword rule that runs before Rules.
 	HighlightRed []string `yaml:"highlight_red" json:"highlight_red"`
 
+	// Rules are applied to every occurrence after recurrence expansion
+	// (filtering, renaming, lane and ink overrides).
+	Rules []RuleConfig `yaml:"rules,omitempty" json:"rules,omitempty"`
+
 	// ICS is the list of subscribed ICS sources.
 	ICS []ICSConfig `yaml:"ics" json:"ics"`
 
+	// Dedup controls merging of the same event reported by several sources.
+	Dedup DedupConfig `yaml:"dedup,omitempty" json:"dedup,omitempty"`
+
+	// Conflicts controls detection of overlapping busy events.
+	Conflicts ConflictConfig `yaml:"conflicts,omitempty" json:"conflicts,omitempty"`
+
+	// Export controls the merged ICS feed (/calendar.ics).
+	Export ExportConfig `yaml:"export,omitempty" json:"export,omitempty"`
+
+	// Metrics controls the Prometheus /metrics endpoint and its auth.
+	Metrics MetricsConfig `yaml:"metrics,omitempty" json:"metrics,omitempty"`
+
+	// TLS enables HTTPS (and optionally an HTTP→HTTPS redirect).
+	TLS TLSConfig `yaml:"tls,omitempty" json:"tls,omitempty"`
+
+	// RateLimit controls failed-login lockout and per-IP rate limits.
+	RateLimit RateLimitConfig `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+
 	// BasicAuth, if non-nil, enables HTTP Basic Authentication on all endpoints
-	// except /health.
+	// except /health (and /metrics when it has its own auth). This account is
+	// always an admin.
 	BasicAuth *BasicAuthConfig `yaml:"basic_auth,omitempty" json:"basic_auth,omitempty"`
+
+	// Users are additional Basic Auth accounts with viewer/admin roles.
+	// Configuring any user (or basic_auth) turns authentication on.
+	Users []UserConfig `yaml:"users,omitempty" json:"users,omitempty"`
+}
+
+// Accounts returns every usable Basic Auth account: basic_auth (as admin)
+// followed by users. Entries without username or password are skipped.
+func (c *Config) Accounts() []UserConfig {
+	out := make([]UserConfig, 0, len(c.Users)+1)
+	if c.BasicAuth != nil && c.BasicAuth.Username != "" && c.BasicAuth.Password != "" {
+		out = append(out, UserConfig{
+			Username: c.BasicAuth.Username,
+			Password: c.BasicAuth.Password,
+			Role:     RoleAdmin,
+		})
+	}
+	for _, u := range c.Users {
+		if u.Username != "" && u.Password != "" {
+			out = append(out, u)
+		}
+	}
+	return out
 }
 
 // DefaultConfig returns an in-memory default configuration.
@@ -162,6 +412,16 @@ func (c *Config) Normalize() {
 	if c.ICS == nil {
 		c.ICS = []ICSConfig{}
 	}
+
+	// Unknown roles fall back to the least privileged one.
+	for i := range c.Users {
+		switch r := strings.ToLower(c.Users[i].Role); r {
+		case RoleAdmin, RoleViewer:
+			c.Users[i].Role = r
+		default:
+			c.Users[i].Role = RoleViewer
+		}
+	}
 }
 
 // Load loads configuration from the given YAML path.
@@ -0,0 +1,143 @@
+package conflict
+
+import (
+	"sort"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// Options controls Detect and Mark.
+type Options struct {
+	// Sources limits detection to occurrences from these source IDs (an
+	// occurrence merged by dedup counts if any of its SourceIDs is listed).
+	// Empty means every source.
+	Sources []string
+}
+
+// Pair is a clash between two busy occurrences, A starting no later than B.
+type Pair struct {
+	A, B model.Occurrence
+
+	// Start / End is the overlapping interval.
+	Start time.Time
+	End   time.Time
+}
+
+// Detect returns every pair of busy occurrences that overlap in time,
+// ordered by overlap start.
+//
+// An occurrence is busy if it is timed (not all-day), opaque (not
+// TRANSP:TRANSPARENT), has a positive duration and belongs to one of
+// opts.Sources. Intervals are half-open, so back-to-back meetings do not
+// clash. Copies of the same instance (same UID and start) never clash with
+// each other; run dedup first to merge them across sources.
+func Detect(occs []model.Occurrence, opts Options) []Pair {
+	var pairs []Pair
+	sweep(occs, opts, func(a, b int) {
+		prev, cur := occs[a], occs[b]
+		end := prev.End
+		if cur.End.Before(end) {
+			end = cur.End
+		}
+		pairs = append(pairs, Pair{A: prev, B: cur, Start: cur.Start, End: end})
+	})
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Start.Before(pairs[j].Start)
+	})
+	return pairs
+}
+
+// Mark sets Conflict on every occurrence of occs (in place) that takes part
+// in a clash, clears it on all others, and returns the number of flagged
+// occurrences.
+func Mark(occs []model.Occurrence, opts Options) int {
+	for i := range occs {
+		occs[i].Conflict = false
+	}
+	n := 0
+	flag := func(i int) {
+		if !occs[i].Conflict {
+			occs[i].Conflict = true
+			n++
+		}
+	}
+	sweep(occs, opts, func(a, b int) {
+		flag(a)
+		flag(b)
+	})
+	return n
+}
+
+// sweep calls fn(a, b) for every clashing pair of busy occurrences, where
+// occs[a] starts no later than occs[b]. It walks the start-sorted intervals
+// keeping the ones still running.
+func sweep(occs []model.Occurrence, opts Options, fn func(a, b int)) {
+	active := make([]int, 0)
+	for _, i := range busyIndexes(occs, opts) {
+		cur := occs[i]
+		kept := active[:0]
+		for _, j := range active {
+			if occs[j].End.After(cur.Start) {
+				kept = append(kept, j)
+			}
+		}
+		active = kept
+
+		for _, j := range active {
+			if occs[j].UID == cur.UID && occs[j].Start.Equal(cur.Start) {
+				continue
+			}
+			fn(j, i)
+		}
+		active = append(active, i)
+	}
+}
+
+// busyIndexes returns the indexes of busy occurrences sorted by start, then
+// end, then UID, so that the output is independent of input order.
+func busyIndexes(occs []model.Occurrence, opts Options) []int {
+	var sources map[string]bool
+	if len(opts.Sources) > 0 {
+		sources = make(map[string]bool, len(opts.Sources))
+		for _, s := range opts.Sources {
+			sources[s] = true
+		}
+	}
+
+	idx := make([]int, 0, len(occs))
+	for i, occ := range occs {
+		if occ.AllDay || occ.Transparent || !occ.End.After(occ.Start) {
+			continue
+		}
+		if sources != nil && !inSources(occ, sources) {
+			continue
+		}
+		idx = append(idx, i)
+	}
+
+	sort.SliceStable(idx, func(a, b int) bool {
+		x, y := occs[idx[a]], occs[idx[b]]
+		if !x.Start.Equal(y.Start) {
+			return x.Start.Before(y.Start)
+		}
+		if !x.End.Equal(y.End) {
+			return x.End.Before(y.End)
+		}
+		return x.UID < y.UID
+	})
+	return idx
+}
+
+func inSources(occ model.Occurrence, sources map[string]bool) bool {
+	if sources[occ.SourceID] {
+		return true
+	}
+	for _, id := range occ.SourceIDs {
+		if sources[id] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package conflict
+
+import (
+	"testing"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+var base = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+// at returns an opaque timed occurrence from base+start to base+end hours.
+func at(uid string, start, end float64) model.Occurrence {
+	return model.Occurrence{
+		SourceID: "work",
+		UID:      uid,
+		Summary:  uid,
+		Start:    base.Add(time.Duration(start * float64(time.Hour))),
+		End:      base.Add(time.Duration(end * float64(time.Hour))),
+	}
+}
+
+func TestDetect(t *testing.T) {
+	allDay := at("holiday", 0, 24)
+	allDay.AllDay = true
+	transparent := at("focus", 0, 3)
+	transparent.Transparent = true
+	personal := at("dentist", 0.5, 1.5)
+	personal.SourceID = "personal"
+	merged := at("lunch", 0.5, 1.5)
+	merged.SourceID, merged.SourceIDs = "personal", []string{"personal", "work"}
+
+	tests := []struct {
+		name    string
+		occs    []model.Occurrence
+		sources []string
+		want    [][2]string // A, B UIDs in order
+	}{
+		{
+			name: "overlap",
+			occs: []model.Occurrence{at("b", 0.5, 2), at("a", 0, 1)},
+			want: [][2]string{{"a", "b"}},
+		},
+		{
+			name: "back-to-back is half-open",
+			occs: []model.Occurrence{at("a", 0, 1), at("b", 1, 2)},
+		},
+		{
+			name: "all-day and transparent are not busy",
+			occs: []model.Occurrence{allDay, transparent, at("a", 0, 1)},
+		},
+		{
+			name: "zero-length is not busy",
+			occs: []model.Occurrence{at("a", 0, 1), at("reminder", 0.5, 0.5)},
+		},
+		{
+			name: "copies of the same instance do not clash",
+			occs: []model.Occurrence{at("a", 0, 1), at("a", 0, 1)},
+		},
+		{
+			name: "nested meetings",
+			occs: []model.Occurrence{at("day", 0, 8), at("a", 1, 2), at("b", 3, 4)},
+			want: [][2]string{{"day", "a"}, {"day", "b"}},
+		},
+		{
+			name:    "source filter",
+			occs:    []model.Occurrence{at("a", 0, 1), personal},
+			sources: []string{"work"},
+		},
+		{
+			name:    "merged occurrence counts for any of its sources",
+			occs:    []model.Occurrence{at("a", 0, 1), merged},
+			sources: []string{"work"},
+			want:    [][2]string{{"a", "lunch"}},
+		},
+		{
+			name: "no filter means every source",
+			occs: []model.Occurrence{at("a", 0, 1), personal},
+			want: [][2]string{{"a", "dentist"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := Detect(tt.occs, Options{Sources: tt.sources})
+			if len(pairs) != len(tt.want) {
+				t.Fatalf("got %d pairs, want %d", len(pairs), len(tt.want))
+			}
+			for i, p := range pairs {
+				if p.A.UID != tt.want[i][0] || p.B.UID != tt.want[i][1] {
+					t.Errorf("pair %d = %s/%s, want %s/%s", i, p.A.UID, p.B.UID, tt.want[i][0], tt.want[i][1])
+				}
+			}
+		})
+	}
+}
+
+func TestDetectOverlapInterval(t *testing.T) {
+	pairs := Detect([]model.Occurrence{at("a", 0, 2), at("b", 1, 3)}, Options{})
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1", len(pairs))
+	}
+	if want := base.Add(time.Hour); !pairs[0].Start.Equal(want) {
+		t.Errorf("overlap start %v, want %v", pairs[0].Start, want)
+	}
+	if want := base.Add(2 * time.Hour); !pairs[0].End.Equal(want) {
+		t.Errorf("overlap end %v, want %v", pairs[0].End, want)
+	}
+}
+
+func TestMark(t *testing.T) {
+	allDay := at("holiday", 0, 24)
+	allDay.AllDay = true
+	stale := at("c", 4, 5)
+	stale.Conflict = true
+
+	occs := []model.Occurrence{at("a", 0, 1), at("b", 0.5, 1.5), at("next", 1.5, 2), allDay, stale}
+	if n := Mark(occs, Options{}); n != 2 {
+		t.Errorf("Mark flagged %d, want 2", n)
+	}
+	want := []bool{true, true, false, false, false}
+	for i, occ := range occs {
+		if occ.Conflict != want[i] {
+			t.Errorf("%s: Conflict = %v, want %v", occ.UID, occ.Conflict, want[i])
+		}
+	}
+}
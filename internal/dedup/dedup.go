@@ -0,0 +1,164 @@
+package dedup
+
+import (
+	"strconv"
+	"strings"
+
+	"epdcal/internal/model"
+)
+
+// Options controls Merge.
+type Options struct {
+	// Precedence lists source IDs from most to least preferred. When
+	// duplicates are merged, the copy from the earliest listed source wins.
+	// Sources not listed rank after all listed ones, in input order.
+	Precedence []string
+
+	// Fuzzy additionally merges occurrences whose UIDs differ but whose
+	// normalized summary, start, end and all-day flag are identical.
+	Fuzzy bool
+}
+
+// Merge collapses the same occurrence reported by several sources into one.
+//
+// Two occurrences from different sources are duplicates if they share the
+// UID and start time, or (with opts.Fuzzy) the normalized summary plus the
+// exact start and end. Occurrences from the same source are never merged,
+// so two genuinely separate events in one calendar stay separate.
+//
+// The winning copy is chosen by opts.Precedence; empty Description,
+// Location and URL fields are filled from the other copies. Every merged
+// occurrence has SourceIDs set to all contributing sources, winner first.
+// The result keeps the input order of the first copy of each occurrence.
+func Merge(occs []model.Occurrence, opts Options) []model.Occurrence {
+	if len(occs) == 0 {
+		return occs
+	}
+
+	rank := make(map[string]int, len(opts.Precedence))
+	for i, id := range opts.Precedence {
+		if _, ok := rank[id]; !ok {
+			rank[id] = i
+		}
+	}
+	rankOf := func(id string) int {
+		if r, ok := rank[id]; ok {
+			return r
+		}
+		return len(opts.Precedence)
+	}
+
+	type group struct {
+		pos     int // input index of the first copy, for output order
+		winner  model.Occurrence
+		sources []string
+	}
+
+	groups := make([]*group, 0, len(occs))
+	byUID := make(map[string]*group, len(occs))
+	byFuzzy := make(map[string]*group)
+
+	for i, occ := range occs {
+		uidKey := occ.UID + "\x00" + strconv.FormatInt(occ.Start.UnixNano(), 10)
+		fuzzyKey := ""
+		if opts.Fuzzy {
+			fuzzyKey = fuzzyKeyOf(occ)
+		}
+
+		g := byUID[uidKey]
+		if g != nil && containsString(g.sources, occ.SourceID) {
+			g = nil
+		}
+		if g == nil && fuzzyKey != "" {
+			if fg := byFuzzy[fuzzyKey]; fg != nil && !containsString(fg.sources, occ.SourceID) {
+				g = fg
+			}
+		}
+
+		if g == nil {
+			g = &group{pos: i, winner: occ, sources: []string{occ.SourceID}}
+			groups = append(groups, g)
+		} else {
+			if rankOf(occ.SourceID) < rankOf(g.winner.SourceID) {
+				g.winner = fillEmpty(occ, g.winner)
+			} else {
+				g.winner = fillEmpty(g.winner, occ)
+			}
+			g.sources = append(g.sources, occ.SourceID)
+		}
+
+		if _, ok := byUID[uidKey]; !ok {
+			byUID[uidKey] = g
+		}
+		if fuzzyKey != "" {
+			if _, ok := byFuzzy[fuzzyKey]; !ok {
+				byFuzzy[fuzzyKey] = g
+			}
+		}
+	}
+
+	out := make([]model.Occurrence, 0, len(groups))
+	for _, g := range groups {
+		occ := g.winner
+		occ.SourceIDs = orderSources(g.sources, occ.SourceID, rankOf)
+		out = append(out, occ)
+	}
+	return out
+}
+
+// fillEmpty returns winner with empty descriptive fields taken from other.
+func fillEmpty(winner, other model.Occurrence) model.Occurrence {
+	if winner.Description == "" {
+		winner.Description = other.Description
+	}
+	if winner.Location == "" {
+		winner.Location = other.Location
+	}
+	if winner.URL == "" {
+		winner.URL = other.URL
+	}
+	return winner
+}
+
+// fuzzyKeyOf builds the summary+start+end key. Summaries are compared
+// case-insensitively with whitespace collapsed; an empty summary never
+// fuzzy-matches.
+func fuzzyKeyOf(occ model.Occurrence) string {
+	summary := strings.ToLower(strings.Join(strings.Fields(occ.Summary), " "))
+	if summary == "" {
+		return ""
+	}
+	return summary + "\x00" +
+		strconv.FormatInt(occ.Start.UnixNano(), 10) + "\x00" +
+		strconv.FormatInt(occ.End.UnixNano(), 10) + "\x00" +
+		strconv.FormatBool(occ.AllDay)
+}
+
+// orderSources returns the contributing source IDs with the winner first
+// and the rest by precedence.
+func orderSources(sources []string, winner string, rankOf func(string) int) []string {
+	out := make([]string, 0, len(sources))
+	out = append(out, winner)
+	rest := make([]string, 0, len(sources)-1)
+	for _, s := range sources {
+		if s != winner {
+			rest = append(rest, s)
+		}
+	}
+	// Insertion sort: the lists are tiny (number of subscribed calendars).
+	for i := 1; i < len(rest); i++ {
+		for j := i; j > 0 && rankOf(rest[j]) < rankOf(rest[j-1]); j-- {
+			rest[j], rest[j-1] = rest[j-1], rest[j]
+		}
+	}
+	return append(out, rest...)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
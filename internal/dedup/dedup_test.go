@@ -0,0 +1,169 @@
+package dedup
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+var (
+	t9  = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	t10 = t9.Add(time.Hour)
+	t11 = t9.Add(2 * time.Hour)
+)
+
+func occ(source, uid, summary string, start, end time.Time) model.Occurrence {
+	return model.Occurrence{SourceID: source, UID: uid, Summary: summary, Start: start, End: end}
+}
+
+func TestMergePrecedenceChoosesWinner(t *testing.T) {
+	in := []model.Occurrence{
+		occ("personal", "standup@corp", "standup (copy)", t9, t10),
+		occ("work", "standup@corp", "Standup", t9, t10),
+		occ("shared", "standup@corp", "Standup shared", t9, t10),
+	}
+
+	out := Merge(in, Options{Precedence: []string{"work", "personal"}})
+	if len(out) != 1 {
+		t.Fatalf("got %d occurrences, want 1", len(out))
+	}
+	if out[0].SourceID != "work" || out[0].Summary != "Standup" {
+		t.Errorf("winner = %s %q, want work %q", out[0].SourceID, out[0].Summary, "Standup")
+	}
+	// Winner first, then by precedence; unlisted sources last.
+	if want := []string{"work", "personal", "shared"}; !slices.Equal(out[0].SourceIDs, want) {
+		t.Errorf("SourceIDs = %v, want %v", out[0].SourceIDs, want)
+	}
+
+	// Without precedence the first copy wins.
+	out = Merge(in, Options{})
+	if out[0].SourceID != "personal" {
+		t.Errorf("winner without precedence = %s, want personal", out[0].SourceID)
+	}
+}
+
+func TestMergeFillsEmptyFieldsFromLosers(t *testing.T) {
+	work := occ("work", "review@corp", "Review", t9, t10)
+	work.Location = "Room 1"
+
+	personal := occ("personal", "review@corp", "Review (mine)", t9, t10)
+	personal.Location = "Home"
+	personal.Description = "Agenda in the doc"
+	personal.URL = "https://meet.example.com/review"
+
+	out := Merge([]model.Occurrence{personal, work}, Options{Precedence: []string{"work"}})
+	if len(out) != 1 {
+		t.Fatalf("got %d occurrences, want 1", len(out))
+	}
+	got := out[0]
+	if got.Summary != "Review" || got.Location != "Room 1" {
+		t.Errorf("winner fields overwritten: summary %q, location %q", got.Summary, got.Location)
+	}
+	if got.Description != personal.Description || got.URL != personal.URL {
+		t.Errorf("empty fields not filled: description %q, url %q", got.Description, got.URL)
+	}
+}
+
+func TestMergeKeepsCopiesFromSameSource(t *testing.T) {
+	in := []model.Occurrence{
+		occ("work", "dup@corp", "Sync", t9, t10),
+		occ("work", "dup@corp", "Sync", t9, t10),
+		occ("work", "other@corp", "Sync", t9, t10),
+	}
+	out := Merge(in, Options{Fuzzy: true})
+	if len(out) != 3 {
+		t.Fatalf("got %d occurrences, want 3 (same source never merges)", len(out))
+	}
+
+	// A second source still merges into the first of the same-source copies.
+	in = append(in, occ("personal", "dup@corp", "Sync", t9, t10))
+	out = Merge(in, Options{})
+	if len(out) != 3 {
+		t.Fatalf("got %d occurrences, want 3", len(out))
+	}
+	if want := []string{"work", "personal"}; !slices.Equal(out[0].SourceIDs, want) {
+		t.Errorf("SourceIDs = %v, want %v", out[0].SourceIDs, want)
+	}
+}
+
+func TestMergeFuzzy(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  model.Occurrence
+		fuzzy bool
+		want  int
+	}{
+		{
+			name: "same UID merges without fuzzy",
+			a:    occ("work", "x@corp", "Planning", t9, t10),
+			b:    occ("personal", "x@corp", "Planning (invite)", t9, t10),
+			want: 1,
+		},
+		{
+			name: "same UID different start stays separate",
+			a:    occ("work", "x@corp", "Planning", t9, t10),
+			b:    occ("personal", "x@corp", "Planning", t10, t11),
+			want: 2,
+		},
+		{
+			name: "different UIDs need fuzzy",
+			a:    occ("work", "a@corp", "Planning", t9, t10),
+			b:    occ("personal", "b@home", "Planning", t9, t10),
+			want: 2,
+		},
+		{
+			name:  "fuzzy ignores case and whitespace",
+			a:     occ("work", "a@corp", "Team  Planning", t9, t10),
+			b:     occ("personal", "b@home", "team planning ", t9, t10),
+			fuzzy: true,
+			want:  1,
+		},
+		{
+			name:  "fuzzy needs the same end",
+			a:     occ("work", "a@corp", "Planning", t9, t10),
+			b:     occ("personal", "b@home", "Planning", t9, t11),
+			fuzzy: true,
+			want:  2,
+		},
+		{
+			name:  "fuzzy never matches empty summaries",
+			a:     occ("work", "a@corp", "", t9, t10),
+			b:     occ("personal", "b@home", "", t9, t10),
+			fuzzy: true,
+			want:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Merge([]model.Occurrence{tt.a, tt.b}, Options{Fuzzy: tt.fuzzy})
+			if len(out) != tt.want {
+				t.Errorf("got %d occurrences, want %d", len(out), tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeKeepsInputOrder(t *testing.T) {
+	in := []model.Occurrence{
+		occ("personal", "b@corp", "B", t10, t11),
+		occ("work", "a@corp", "A", t9, t10),
+		occ("work", "b@corp", "B", t10, t11),
+	}
+	out := Merge(in, Options{Precedence: []string{"work"}})
+	if len(out) != 2 || out[0].UID != "b@corp" || out[1].UID != "a@corp" {
+		t.Fatalf("order = %v, want b@corp, a@corp", uids(out))
+	}
+	if out[0].SourceID != "work" {
+		t.Errorf("winner = %s, want work", out[0].SourceID)
+	}
+}
+
+func uids(occs []model.Occurrence) []string {
+	out := make([]string, len(occs))
+	for i, o := range occs {
+		out[i] = o.UID
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package eventbus
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the refresh / render pipeline and the web server.
+const (
+	CycleStarted     = "cycle_started"
+	SourceFetched    = "source_fetched"
+	CycleCompleted   = "cycle_completed"
+	RenderCompleted  = "render_completed"
+	DisplayCompleted = "display_completed"
+	ConfigChanged    = "config_changed"
+	BatteryUpdated   = "battery_updated"
+)
+
+// Event is a single notification. Data must be JSON-serializable.
+type Event struct {
+	// ID increases by one per published event, so a client can tell that it
+	// missed some.
+	ID   uint64
+	Type string
+	Time time.Time
+	Data any
+}
+
+// Bus fans events out to subscribers. Publish never blocks: each subscriber
+// has its own buffered channel, and a subscriber whose buffer is full is
+// dropped (its channel is closed) instead of stalling the pipeline. SSE
+// clients simply reconnect and resync.
+//
+// A nil *Bus is valid and discards everything.
+type Bus struct {
+	mu     sync.Mutex
+	nextID uint64
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription receives events on C until it is closed by Close, by the bus
+// shutting down, or by falling behind.
+type Subscription struct {
+	C <-chan Event
+
+	bus     *Bus
+	ch      chan Event
+	dropped bool
+}
+
+// New returns an empty Bus.
+func New() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Publish sends an event of the given type to every subscriber.
+func (b *Bus) Publish(typ string, data any) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.nextID++
+	ev := Event{ID: b.nextID, Type: typ, Time: time.Now(), Data: data}
+	for s := range b.subs {
+		select {
+		case s.ch <- ev:
+		default:
+			// Slow consumer: disconnect rather than block or silently skip.
+			s.dropped = true
+			delete(b.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber with the given channel buffer. On a
+// closed (or nil) bus the returned subscription's channel is already closed.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, max(buffer, 1))
+	s := &Subscription{C: ch, bus: b, ch: ch}
+	if b == nil {
+		close(ch)
+		return s
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Close unregisters s. It is safe to call more than once.
+func (s *Subscription) Close() {
+	b := s.bus
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Dropped reports whether s was disconnected for falling behind. Only
+// meaningful after C has been closed.
+func (s *Subscription) Dropped() bool {
+	if s.bus == nil {
+		return false
+	}
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
+
+// Subscribers returns the number of active subscriptions.
+func (b *Bus) Subscribers() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Shutdown closes every subscription and turns later Publish calls into
+// no-ops. The web server calls it when it starts draining so that
+// long-lived SSE responses finish.
+func (b *Bus) Shutdown() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
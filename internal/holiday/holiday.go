@@ -0,0 +1,261 @@
+package holiday
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// Substitute describes when a holiday earns a substitute day off.
+type Substitute int
+
+const (
+	// NoSubstitute: a holiday lost to a weekend or another holiday is lost.
+	NoSubstitute Substitute = iota
+	// SubstituteSunday: a substitute day is granted if any day of the
+	// holiday falls on a Sunday or coincides with another holiday
+	// (Korean rule for the three-day Seollal/Chuseok breaks).
+	SubstituteSunday
+	// SubstituteWeekend: a substitute day is granted if the holiday falls on
+	// a Saturday or Sunday or coincides with another holiday.
+	SubstituteWeekend
+)
+
+// Rule is one row of a country's holiday table.
+type Rule struct {
+	Name string
+
+	// Month / Day of the holiday; lunar months/days if Lunar is set.
+	Month int
+	Day   int
+	Lunar bool
+
+	// DaysBefore / DaysAfter extend the holiday into a break (e.g. Seollal
+	// is lunar 1/1 plus the day before and after). BeforeName / AfterName
+	// label those days; they default to Name.
+	DaysBefore int
+	DaysAfter  int
+	BeforeName string
+	AfterName  string
+
+	// Since / Until restrict the rule to Gregorian years (inclusive; 0 means
+	// unbounded).
+	Since int
+	Until int
+
+	// Substitute applies to holidays on or after SubstituteSince.
+	Substitute      Substitute
+	SubstituteSince time.Time
+}
+
+// Country is a holiday table plus the calendar conventions it needs.
+type Country struct {
+	Code string
+
+	// Location defines day boundaries for lunar dates and for the dates the
+	// table produces (KST for Korea).
+	Location *time.Location
+
+	// SubstituteName labels substitute days.
+	SubstituteName string
+
+	// Rules are evaluated per Gregorian year.
+	Rules []Rule
+}
+
+// Holiday is a single public holiday date.
+type Holiday struct {
+	Date time.Time // 00:00 in the country's Location
+	Name string
+
+	// Substitute is true for a substitute day; Reason then names the
+	// holiday it replaces.
+	Substitute bool
+	Reason     string
+}
+
+var countries = map[string]*Country{}
+
+// Register adds or replaces a country table. Tables for built-in countries
+// are registered at init.
+func Register(c *Country) {
+	countries[strings.ToUpper(c.Code)] = c
+}
+
+// Lookup returns the table for an ISO 3166-1 alpha-2 country code.
+func Lookup(code string) (*Country, bool) {
+	c, ok := countries[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// Holidays returns every public holiday of the Gregorian year, including
+// substitute days, sorted by date.
+func (c *Country) Holidays(year int) []Holiday {
+	type entry struct {
+		Holiday
+		rule    *Rule
+		spanEnd time.Time // last day of the break this date belongs to
+	}
+
+	var entries []entry
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if (r.Since != 0 && year < r.Since) || (r.Until != 0 && year > r.Until) {
+			continue
+		}
+
+		var main time.Time
+		if r.Lunar {
+			main = lunarDate(year, r.Month, r.Day, c.Location)
+		} else {
+			main = time.Date(year, time.Month(r.Month), r.Day, 0, 0, 0, 0, c.Location)
+		}
+
+		var span []time.Time
+		for d := -r.DaysBefore; d <= r.DaysAfter; d++ {
+			span = append(span, main.AddDate(0, 0, d))
+		}
+		for _, day := range span {
+			name := r.Name
+			switch {
+			case day.Before(main) && r.BeforeName != "":
+				name = r.BeforeName
+			case day.After(main) && r.AfterName != "":
+				name = r.AfterName
+			}
+			entries = append(entries, entry{
+				Holiday: Holiday{Date: day, Name: name},
+				rule:    r,
+				spanEnd: span[len(span)-1],
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+
+	taken := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		taken[dayKey(e.Date)] = true
+	}
+
+	out := make([]Holiday, 0, len(entries)+4)
+	for _, e := range entries {
+		out = append(out, e.Holiday)
+	}
+
+	// Substitute days: walk the dates in order. On each date count the
+	// holidays that are "lost" — all eligible ones on a qualifying weekend
+	// day, otherwise all but one when several coincide — and grant one
+	// substitute day per lost holiday after the end of its break.
+	for i := 0; i < len(entries); {
+		j := i
+		for j < len(entries) && entries[j].Date.Equal(entries[i].Date) {
+			j++
+		}
+		group := entries[i:j]
+		i = j
+
+		date := group[0].Date
+		var eligible []entry
+		for _, e := range group {
+			if e.rule.Substitute == NoSubstitute || date.Before(e.rule.SubstituteSince) {
+				continue
+			}
+			eligible = append(eligible, e)
+		}
+		if len(eligible) == 0 {
+			continue
+		}
+
+		var lost []entry
+		for _, e := range eligible {
+			wd := date.Weekday()
+			if wd == time.Sunday || (wd == time.Saturday && e.rule.Substitute == SubstituteWeekend) {
+				lost = append(lost, e)
+			}
+		}
+		if len(lost) == 0 && len(group) > 1 {
+			// Several holidays share a weekday: only one of them is observed.
+			lost = eligible
+			if len(eligible) == len(group) {
+				lost = eligible[1:]
+			}
+		}
+
+		for _, e := range lost {
+			d := e.spanEnd.AddDate(0, 0, 1)
+			for isWeekend(d) || taken[dayKey(d)] {
+				d = d.AddDate(0, 0, 1)
+			}
+			taken[dayKey(d)] = true
+			out = append(out, Holiday{
+				Date:       d,
+				Name:       c.SubstituteName,
+				Substitute: true,
+				Reason:     e.rule.Name,
+			})
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Date.Before(out[j].Date)
+	})
+	return out
+}
+
+// Occurrences returns the country's holidays overlapping
+// [rangeStart, rangeEnd] as all-day occurrences for sourceID, in loc.
+func (c *Country) Occurrences(sourceID string, loc *time.Location, rangeStart, rangeEnd time.Time) []model.Occurrence {
+	if loc == nil {
+		loc = time.Local
+	}
+	out := make([]model.Occurrence, 0)
+	for y := rangeStart.Year(); y <= rangeEnd.Year(); y++ {
+		seq := make(map[string]int)
+		for _, h := range c.Holidays(y) {
+			// Holidays are calendar dates: re-anchor them in the display
+			// timezone rather than converting the instant.
+			start := time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, loc)
+			end := start.AddDate(0, 0, 1)
+			if end.Before(rangeStart) || start.After(rangeEnd) {
+				continue
+			}
+
+			// Several holidays can share a date (e.g. 어린이날 and
+			// 부처님오신날 in 2025), so the UID carries a per-date sequence.
+			n := seq[dayKey(h.Date)]
+			seq[dayKey(h.Date)]++
+
+			desc := "공휴일"
+			if h.Substitute {
+				desc = fmt.Sprintf("공휴일 (%s 대체)", h.Reason)
+			}
+			out = append(out, model.Occurrence{
+				SourceID:    sourceID,
+				UID:         fmt.Sprintf("%s-%s-%d-%s@epdcal", strings.ToLower(c.Code), h.Date.Format("20060102"), n, sourceID),
+				InstanceKey: start.Format(time.RFC3339Nano),
+				Summary:     h.Name,
+				Description: desc,
+				Categories:  []string{"holiday"},
+				AllDay:      true,
+				Start:       start,
+				End:         end,
+			})
+		}
+	}
+	return out
+}
+
+func isWeekend(d time.Time) bool {
+	wd := d.Weekday()
+	return wd == time.Saturday || wd == time.Sunday
+}
+
+func dayKey(d time.Time) string {
+	return d.Format("2006-01-02")
+}
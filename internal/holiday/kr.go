@@ -0,0 +1,54 @@
+package holiday
+
+import "time"
+
+// kst is the reference meridian of the Korean lunar calendar (UTC+9 since
+// 1961).
+var kst = time.FixedZone("KST", 9*60*60)
+
+// Dates from which 대체공휴일 applies to each group of holidays:
+//   - 2014: 설날·추석 연휴 (Sunday or overlap), 어린이날 (weekend or overlap)
+//   - 2021-08-04: 삼일절·광복절·개천절·한글날
+//   - 2023-05-04: 부처님오신날·기독탄신일
+var (
+	krSubstitute2014 = time.Date(2014, 1, 1, 0, 0, 0, 0, kst)
+	krSubstitute2021 = time.Date(2021, 8, 4, 0, 0, 0, 0, kst)
+	krSubstitute2023 = time.Date(2023, 5, 4, 0, 0, 0, 0, kst)
+)
+
+// KR is the South Korean public holiday table (관공서의 공휴일에 관한 규정).
+//
+// Election days and one-off temporary holidays (임시공휴일) are announced
+// case by case and are not part of the table.
+var KR = &Country{
+	Code:           "KR",
+	Location:       kst,
+	SubstituteName: "대체공휴일",
+	Rules: []Rule{
+		{Name: "신정", Month: 1, Day: 1},
+		{
+			Name: "설날", Month: 1, Day: 1, Lunar: true,
+			DaysBefore: 1, DaysAfter: 1, BeforeName: "설날 연휴", AfterName: "설날 연휴",
+			Substitute: SubstituteSunday, SubstituteSince: krSubstitute2014,
+		},
+		{Name: "삼일절", Month: 3, Day: 1, Substitute: SubstituteWeekend, SubstituteSince: krSubstitute2021},
+		{Name: "식목일", Month: 4, Day: 5, Until: 2005},
+		{Name: "부처님오신날", Month: 4, Day: 8, Lunar: true, Substitute: SubstituteWeekend, SubstituteSince: krSubstitute2023},
+		{Name: "어린이날", Month: 5, Day: 5, Substitute: SubstituteWeekend, SubstituteSince: krSubstitute2014},
+		{Name: "현충일", Month: 6, Day: 6},
+		{Name: "제헌절", Month: 7, Day: 17, Until: 2007},
+		{Name: "광복절", Month: 8, Day: 15, Substitute: SubstituteWeekend, SubstituteSince: krSubstitute2021},
+		{
+			Name: "추석", Month: 8, Day: 15, Lunar: true,
+			DaysBefore: 1, DaysAfter: 1, BeforeName: "추석 연휴", AfterName: "추석 연휴",
+			Substitute: SubstituteSunday, SubstituteSince: krSubstitute2014,
+		},
+		{Name: "개천절", Month: 10, Day: 3, Substitute: SubstituteWeekend, SubstituteSince: krSubstitute2021},
+		{Name: "한글날", Month: 10, Day: 9, Since: 2013, Substitute: SubstituteWeekend, SubstituteSince: krSubstitute2021},
+		{Name: "기독탄신일", Month: 12, Day: 25, Substitute: SubstituteWeekend, SubstituteSince: krSubstitute2023},
+	},
+}
+
+func init() {
+	Register(KR)
+}
@@ -0,0 +1,143 @@
+package holiday
+
+import (
+	"testing"
+	"time"
+)
+
+// krHolidays indexes KR.Holidays(year) by date for lookups in tests.
+func krHolidays(t *testing.T, year int) map[string][]Holiday {
+	t.Helper()
+	byDate := make(map[string][]Holiday)
+	for _, h := range KR.Holidays(year) {
+		if h.Date.Location() != kst {
+			t.Fatalf("%s %s: location %v, want KST", dayKey(h.Date), h.Name, h.Date.Location())
+		}
+		byDate[dayKey(h.Date)] = append(byDate[dayKey(h.Date)], h)
+	}
+	return byDate
+}
+
+func TestKRHolidays(t *testing.T) {
+	tests := []struct {
+		date       string
+		name       string
+		substitute bool
+		reason     string
+	}{
+		// 설날 2023: lunar 1/1 is Sunday 01-22, so the break earns 01-24.
+		{"2023-01-21", "설날 연휴", false, ""},
+		{"2023-01-22", "설날", false, ""},
+		{"2023-01-23", "설날 연휴", false, ""},
+		{"2023-01-24", "대체공휴일", true, "설날"},
+
+		// 부처님오신날 2025 shares 05-05 with 어린이날.
+		{"2025-05-05", "부처님오신날", false, ""},
+		{"2025-05-05", "어린이날", false, ""},
+		{"2025-05-06", "대체공휴일", true, "어린이날"},
+
+		// 추석 2025 starts on a Sunday; the substitute comes right after
+		// the break, before 한글날.
+		{"2025-10-05", "추석 연휴", false, ""},
+		{"2025-10-06", "추석", false, ""},
+		{"2025-10-07", "추석 연휴", false, ""},
+		{"2025-10-08", "대체공휴일", true, "추석"},
+		{"2025-10-09", "한글날", false, ""},
+
+		// 추석 2028 coincides with 개천절 on 10-03.
+		{"2028-10-02", "추석 연휴", false, ""},
+		{"2028-10-03", "추석", false, ""},
+		{"2028-10-03", "개천절", false, ""},
+		{"2028-10-04", "추석 연휴", false, ""},
+		{"2028-10-05", "대체공휴일", true, "개천절"},
+
+		// 추석 2029 ends on a Sunday.
+		{"2029-09-21", "추석 연휴", false, ""},
+		{"2029-09-22", "추석", false, ""},
+		{"2029-09-23", "추석 연휴", false, ""},
+		{"2029-09-24", "대체공휴일", true, "추석"},
+	}
+
+	years := make(map[int]map[string][]Holiday)
+	for _, tt := range tests {
+		d, err := time.ParseInLocation("2006-01-02", tt.date, kst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if years[d.Year()] == nil {
+			years[d.Year()] = krHolidays(t, d.Year())
+		}
+
+		found := false
+		for _, h := range years[d.Year()][tt.date] {
+			if h.Name != tt.name {
+				continue
+			}
+			found = true
+			if h.Substitute != tt.substitute || h.Reason != tt.reason {
+				t.Errorf("%s %s: substitute=%v reason=%q, want %v %q", tt.date, tt.name, h.Substitute, h.Reason, tt.substitute, tt.reason)
+			}
+		}
+		if !found {
+			t.Errorf("%s: no %s in %v", tt.date, tt.name, years[d.Year()][tt.date])
+		}
+	}
+}
+
+// TestKRSubstituteCount guards against a break or an overlap granting more
+// than one substitute day.
+func TestKRSubstituteCount(t *testing.T) {
+	tests := []struct {
+		year int
+		want []string
+	}{
+		{2023, []string{"2023-01-24", "2023-05-29"}},
+		{2025, []string{"2025-03-03", "2025-05-06", "2025-10-08"}},
+		{2028, []string{"2028-10-05"}},
+		{2029, []string{"2029-05-07", "2029-05-21", "2029-09-24"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, h := range KR.Holidays(tt.year) {
+			if h.Substitute {
+				got = append(got, dayKey(h.Date))
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%d: substitute days %v, want %v", tt.year, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%d: substitute days %v, want %v", tt.year, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestLunarDate checks the astronomical conversion against published KASI
+// dates, including a year with a leap month before 추석 (2025, 윤6월).
+func TestLunarDate(t *testing.T) {
+	tests := []struct {
+		year, month, day int
+		want             string
+	}{
+		{2023, 1, 1, "2023-01-22"},
+		{2024, 1, 1, "2024-02-10"},
+		{2025, 1, 1, "2025-01-29"},
+		{2025, 4, 8, "2025-05-05"},
+		{2025, 8, 15, "2025-10-06"},
+		{2028, 8, 15, "2028-10-03"},
+		{2029, 8, 15, "2029-09-22"},
+	}
+	for _, tt := range tests {
+		got := lunarDate(tt.year, tt.month, tt.day, kst)
+		if dayKey(got) != tt.want {
+			t.Errorf("lunar %d-%02d-%02d = %s, want %s", tt.year, tt.month, tt.day, dayKey(got), tt.want)
+		}
+		if got.Hour() != 0 || got.Minute() != 0 {
+			t.Errorf("lunar %d-%02d-%02d = %v, want midnight", tt.year, tt.month, tt.day, got)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package holiday
+
+import (
+	"math"
+	"time"
+)
+
+// Lunisolar calendar support (Korean 음력 / Chinese 農曆) computed from
+// astronomical new moons and principal solar terms, so no lookup tables or
+// network access are needed.
+//
+//   - A lunar month starts on the local calendar day of a new moon.
+//   - Month 11 is the month containing the winter solstice (270°). If 13
+//     months separate two such months, the first of them without a principal
+//     solar term (a multiple of 30°) is the leap month; the others are
+//     numbered 12, 1, 2, … in order.
+//
+// New moons use Meeus, Astronomical Algorithms ch. 49 (error well below a
+// minute); solar longitude uses the low-precision solar theory of ch. 25
+// (~0.01°, i.e. ~15 minutes). Both are ample for the years the panel shows.
+
+const (
+	synodicMonth = 29.530588861
+	tropicalYear = 365.242189
+	jdUnixEpoch  = 2440587.5
+)
+
+// lunarDate returns the Gregorian date (00:00 in loc) of lunar month/day in
+// the lunar year that starts in Gregorian year y. Day boundaries are taken
+// in loc, which must be the calendar's reference meridian (KST for Korea).
+func lunarDate(y, month, day int, loc *time.Location) time.Time {
+	start := lunarMonthStart(y, month, loc)
+	return start.AddDate(0, 0, day-1)
+}
+
+// lunarMonthStart returns the first day of regular lunar month (1–12) of
+// lunar year y.
+func lunarMonthStart(y, month int, loc *time.Location) time.Time {
+	// Months are counted from the 11th month (the one containing the
+	// winter solstice). Month 12 of year y follows the 11th month of y; all
+	// other months follow the 11th month of y-1.
+	from := y - 1
+	steps := (month + 1) % 12 // M12 → 1, M1 → 2, …, M10 → 11, M11 → 12
+	if month == 12 {
+		from = y
+		steps = 1
+	}
+
+	k, leap := solsticeMonths(from, loc)
+	for n := 0; n < steps; {
+		k++
+		if k != leap {
+			n++
+		}
+	}
+	return dateOf(jdToTime(newMoonJDE(k)), loc)
+}
+
+// solsticeMonths returns the new moon number of the 11th month of lunar
+// year y (the month containing the December solstice of y) and the new
+// moon number of the leap month between it and the following 11th month,
+// or -1 if that span has no leap month.
+//
+// A span of 13 months has exactly one leap month: the first one that
+// contains no principal solar term (中氣). Choosing it this way, rather than
+// "the month containing term X is month N", is what resolves years such as
+// 2033 where one month holds two principal terms.
+func solsticeMonths(y int, loc *time.Location) (k11 float64, leap float64) {
+	k11 = monthContaining(solarTermJDE(y, 270), loc)
+	next := monthContaining(solarTermJDE(y+1, 270), loc)
+	leap = -1
+	if next-k11 != 13 {
+		return k11, leap
+	}
+	for k := k11 + 1; k < next; k++ {
+		start := dateOf(jdToTime(newMoonJDE(k)), loc)
+		end := dateOf(jdToTime(newMoonJDE(k+1)), loc)
+		a := math.Floor(apparentSolarLongitude(timeToJDE(start)) / 30)
+		b := math.Floor(apparentSolarLongitude(timeToJDE(end)) / 30)
+		if a == b {
+			return k11, k
+		}
+	}
+	return k11, leap
+}
+
+// monthContaining returns the new moon number k of the lunar month whose
+// local date span contains the local date of jde.
+func monthContaining(jde float64, loc *time.Location) float64 {
+	day := dateOf(jdToTime(jde), loc)
+	k := math.Floor((jde - 2451550.09766) / synodicMonth)
+	for dateOf(jdToTime(newMoonJDE(k+1)), loc).Compare(day) <= 0 {
+		k++
+	}
+	for dateOf(jdToTime(newMoonJDE(k)), loc).After(day) {
+		k--
+	}
+	return k
+}
+
+// solarTermJDE returns the JDE (TT) at which the apparent solar longitude
+// reaches target degrees, for the occurrence belonging to lunar year y
+// (330° in February of y through 300° in January of y+1).
+func solarTermJDE(y int, target float64) float64 {
+	// 330° is reached around Feb 18–19.
+	approx := timeToJD(time.Date(y, time.February, 18, 0, 0, 0, 0, time.UTC)) +
+		math.Mod(target-330+360, 360)*tropicalYear/360
+	jde := approx
+	for i := 0; i < 8; i++ {
+		diff := math.Mod(target-apparentSolarLongitude(jde)+540, 360) - 180
+		jde += diff * tropicalYear / 360
+		if math.Abs(diff) < 1e-6 {
+			break
+		}
+	}
+	return jde
+}
+
+// apparentSolarLongitude returns the Sun's apparent geocentric longitude in
+// degrees for JDE (Meeus ch. 25, low accuracy).
+func apparentSolarLongitude(jde float64) float64 {
+	t := (jde - 2451545.0) / 36525
+	l0 := 280.46646 + 36000.76983*t + 0.0003032*t*t
+	m := rad(357.52911 + 35999.05029*t - 0.0001537*t*t)
+	c := (1.914602-0.004817*t-0.000014*t*t)*math.Sin(m) +
+		(0.019993-0.000101*t)*math.Sin(2*m) +
+		0.000289*math.Sin(3*m)
+	omega := rad(125.04 - 1934.136*t)
+	lambda := l0 + c - 0.00569 - 0.00478*math.Sin(omega)
+	return math.Mod(math.Mod(lambda, 360)+360, 360)
+}
+
+// newMoonJDE returns the JDE (TT) of new moon number k (k = 0 is the new
+// moon of 2000-01-06), Meeus ch. 49.
+func newMoonJDE(k float64) float64 {
+	t := k / 1236.85
+	t2, t3, t4 := t*t, t*t*t, t*t*t*t
+
+	jde := 2451550.09766 + synodicMonth*k + 0.00015437*t2 - 0.000000150*t3 + 0.00000000073*t4
+	e := 1 - 0.002516*t - 0.0000074*t2
+	m := rad(2.5534 + 29.10535670*k - 0.0000014*t2 - 0.00000011*t3)
+	mp := rad(201.5643 + 385.81693528*k + 0.0107582*t2 + 0.00001238*t3 - 0.000000058*t4)
+	f := rad(160.7108 + 390.67050284*k - 0.0016118*t2 - 0.00000227*t3 + 0.000000011*t4)
+	om := rad(124.7746 - 1.56375588*k + 0.0020672*t2 + 0.00000215*t3)
+
+	jde += -0.40720*math.Sin(mp) +
+		0.17241*e*math.Sin(m) +
+		0.01608*math.Sin(2*mp) +
+		0.01039*math.Sin(2*f) +
+		0.00739*e*math.Sin(mp-m) -
+		0.00514*e*math.Sin(mp+m) +
+		0.00208*e*e*math.Sin(2*m) -
+		0.00111*math.Sin(mp-2*f) -
+		0.00057*math.Sin(mp+2*f) +
+		0.00056*e*math.Sin(2*mp+m) -
+		0.00042*math.Sin(3*mp) +
+		0.00042*e*math.Sin(m+2*f) +
+		0.00038*e*math.Sin(m-2*f) -
+		0.00024*e*math.Sin(2*mp-m) -
+		0.00017*math.Sin(om) -
+		0.00007*math.Sin(mp+2*m) +
+		0.00004*math.Sin(2*mp-2*f) +
+		0.00004*math.Sin(3*m) +
+		0.00003*math.Sin(mp+m-2*f) +
+		0.00003*math.Sin(2*mp+2*f) -
+		0.00003*math.Sin(mp+m+2*f) +
+		0.00003*math.Sin(mp-m+2*f) -
+		0.00002*math.Sin(mp-m-2*f) -
+		0.00002*math.Sin(3*mp+m) +
+		0.00002*math.Sin(4*mp)
+
+	// Planetary arguments.
+	planetary := [14][3]float64{
+		{299.77, 0.107408, 0.000325},
+		{251.88, 0.016321, 0.000165},
+		{251.83, 26.651886, 0.000164},
+		{349.42, 36.412478, 0.000126},
+		{84.66, 18.206239, 0.000110},
+		{141.74, 53.303771, 0.000062},
+		{207.14, 2.453732, 0.000060},
+		{154.84, 7.306860, 0.000056},
+		{34.52, 27.261239, 0.000047},
+		{207.19, 0.121824, 0.000042},
+		{291.34, 1.844379, 0.000040},
+		{161.72, 24.198154, 0.000037},
+		{239.56, 25.513099, 0.000035},
+		{331.55, 3.592518, 0.000023},
+	}
+	for i, p := range planetary {
+		arg := p[0] + p[1]*k
+		if i == 0 {
+			arg -= 0.009173 * t2
+		}
+		jde += p[2] * math.Sin(rad(arg))
+	}
+	return jde
+}
+
+// deltaT returns TT − UT in seconds (Espenak & Meeus polynomials).
+func deltaT(year float64) float64 {
+	switch {
+	case year < 2005:
+		t := year - 2000
+		return 63.86 + 0.3345*t - 0.060374*t*t + 0.0017275*t*t*t
+	case year < 2050:
+		t := year - 2000
+		return 62.92 + 0.32217*t + 0.005589*t*t
+	default:
+		u := (year - 1820) / 100
+		return -20 + 32*u*u - 0.5628*(2150-year)
+	}
+}
+
+// jdToTime converts a JDE (TT) to a UTC time.
+func jdToTime(jde float64) time.Time {
+	year := 2000 + (jde-2451545.0)/tropicalYear
+	jd := jde - deltaT(year)/86400
+	sec := (jd - jdUnixEpoch) * 86400
+	return time.Unix(0, int64(sec*1e9)).UTC()
+}
+
+func timeToJD(t time.Time) float64 {
+	return float64(t.UnixNano())/86400e9 + jdUnixEpoch
+}
+
+// timeToJDE converts a time to a JDE (TT).
+func timeToJDE(t time.Time) float64 {
+	year := float64(t.Year()) + float64(t.YearDay())/365.25
+	return timeToJD(t) + deltaT(year)/86400
+}
+
+// dateOf returns 00:00 of t's calendar date in loc.
+func dateOf(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
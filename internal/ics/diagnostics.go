@@ -0,0 +1,91 @@
+package ics
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// Severity classifies a Diagnostic.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Diagnostic is a single structured problem found while parsing or
+// expanding an ICS source.
+type Diagnostic struct {
+	SourceID string   `json:"source_id"`
+	UID      string   `json:"uid,omitempty"`
+	Line     int      `json:"line,omitempty"` // 1-based line of the component's BEGIN, 0 if unknown
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Diagnostics collects Diagnostic entries from the parse and expand stages.
+// A nil *Diagnostics is valid and discards everything, so callers that do
+// not care can leave ParseConfig.Diagnostics / ExpandConfig.Diagnostics nil.
+type Diagnostics struct {
+	mu    sync.Mutex
+	items []Diagnostic
+}
+
+// Add records a diagnostic. It is safe for concurrent use.
+func (d *Diagnostics) Add(diag Diagnostic) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.items = append(d.items, diag)
+	d.mu.Unlock()
+}
+
+// Items returns a copy of all recorded diagnostics in insertion order.
+func (d *Diagnostics) Items() []Diagnostic {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Diagnostic, len(d.items))
+	copy(out, d.items)
+	return out
+}
+
+// Count returns the number of diagnostics with the given severity.
+func (d *Diagnostics) Count(sev Severity) int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, it := range d.items {
+		if it.Severity == sev {
+			n++
+		}
+	}
+	return n
+}
+
+// componentLines returns the 1-based line numbers of every "BEGIN:<name>"
+// line in body, in document order. golang-ical does not track positions, so
+// the parser maps the i-th component it sees to the i-th entry here.
+func componentLines(body []byte, name string) []int {
+	marker := "BEGIN:" + name
+	var lines []int
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for sc.Scan() {
+		n++
+		if strings.EqualFold(strings.TrimSpace(sc.Text()), marker) {
+			lines = append(lines, n)
+		}
+	}
+	return lines
+}
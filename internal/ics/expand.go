@@ -2,6 +2,7 @@ package ics
 
 import (
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/teambition/rrule-go"
@@ -27,6 +28,27 @@ type ExpandConfig struct {
 	// MaxOccurrencesPerEvent is a safety cap to avoid infinite or extremely
 	// large expansions. If zero, defaultMaxOccurrencesPerEvent is used.
 	MaxOccurrencesPerEvent int
+
+	// Diagnostics, if non-nil, collects structured expansion problems
+	// (invalid RRULE, truncated expansions).
+	Diagnostics *Diagnostics
+
+	// Cache, if non-nil, memoizes per-UID expansion results so that
+	// unchanged events are not re-expanded on every call.
+	Cache *ExpandCache
+}
+
+// DayWindow returns the expansion window used for refresh cycles:
+// from 00:00 daysBefore days before now's date to 00:00 after the date
+// daysAfter days ahead, in now's location.
+//
+// Aligning both ends to local midnight keeps the window, and therefore the
+// ExpandCache and SourceCache keys, identical for every refresh of the same
+// day; a window derived from the exact current time would never hit.
+func DayWindow(now time.Time, daysBefore, daysAfter int) (time.Time, time.Time) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	return today.AddDate(0, 0, -daysBefore), today.AddDate(0, 0, daysAfter+1)
 }
 
 // ExpandResult wraps the list of expanded occurrences and optionally
@@ -75,18 +97,41 @@ func ExpandOccurrences(events []ParsedEvent, cfg ExpandConfig) (ExpandResult, er
 	}
 
 	allOccurrences := make([]model.Occurrence, 0)
+	cfg.Cache.prune(time.Now())
 
 	for uid, baseEvents := range baseByUID {
 		ov := overridesByUID[uid]
-		truncated := false
 
-		for _, ev := range baseEvents {
-			occ, hitCap := expandEvent(ev, ov, cfg)
-			if hitCap {
-				truncated = true
+		var (
+			occs      []model.Occurrence
+			truncated bool
+			key       string
+			cached    bool
+		)
+		if cfg.Cache != nil {
+			key = expandCacheKey(baseEvents, ov, cfg)
+			occs, truncated, cached = cfg.Cache.get(key)
+		}
+
+		if !cached {
+			cacheable := true
+			for _, ev := range baseEvents {
+				occ, hitCap, err := expandEvent(ev, ov, cfg)
+				if err != nil {
+					// Invalid RRULE: do not cache so the diagnostic is
+					// reported again on the next call.
+					cacheable = false
+				}
+				if hitCap {
+					truncated = true
+				}
+				occs = append(occs, occ...)
+			}
+			if cfg.Cache != nil && cacheable {
+				cfg.Cache.put(key, occs, truncated)
 			}
-			allOccurrences = append(allOccurrences, occ...)
 		}
+		allOccurrences = append(allOccurrences, occs...)
 
 		if truncated {
 			result.TruncatedEvents = append(result.TruncatedEvents, uid)
@@ -95,6 +140,28 @@ func ExpandOccurrences(events []ParsedEvent, cfg ExpandConfig) (ExpandResult, er
 				"uid", uid,
 				"cap", cfg.MaxOccurrencesPerEvent,
 			)
+			cfg.Diagnostics.Add(Diagnostic{
+				SourceID: baseEvents[0].Source.ID,
+				UID:      uid,
+				Line:     baseEvents[0].Line,
+				Severity: SeverityWarning,
+				Message:  "expansion truncated at " + strconv.Itoa(cfg.MaxOccurrencesPerEvent) + " occurrences",
+			})
+		}
+	}
+
+	if cfg.Diagnostics != nil {
+		for uid, ov := range overridesByUID {
+			if _, ok := baseByUID[uid]; ok {
+				continue
+			}
+			cfg.Diagnostics.Add(Diagnostic{
+				SourceID: ov[0].Source.ID,
+				UID:      uid,
+				Line:     ov[0].Line,
+				Severity: SeverityWarning,
+				Message:  "RECURRENCE-ID override without a base event; ignored",
+			})
 		}
 	}
 
@@ -103,12 +170,12 @@ func ExpandOccurrences(events []ParsedEvent, cfg ExpandConfig) (ExpandResult, er
 }
 
 // expandEvent expands a single ParsedEvent (base event) with its possible
-// overrides within the given configuration, returning occurrences and whether
-// the cap was hit.
-func expandEvent(ev ParsedEvent, overrides []ParsedEvent, cfg ExpandConfig) ([]model.Occurrence, bool) {
+// overrides within the given configuration, returning occurrences, whether
+// the cap was hit and the RRULE error (already logged and reported), if any.
+func expandEvent(ev ParsedEvent, overrides []ParsedEvent, cfg ExpandConfig) ([]model.Occurrence, bool, error) {
 	// Single non-recurring event
 	if ev.RawRRule == "" {
-		return expandSingleEvent(ev, overrides, cfg), false
+		return expandSingleEvent(ev, overrides, cfg), false, nil
 	}
 
 	// Recurring event via RRULE
@@ -137,55 +204,80 @@ func expandSingleEvent(ev ParsedEvent, overrides []ParsedEvent, cfg ExpandConfig
 	return out
 }
 
-func expandRecurringEvent(ev ParsedEvent, overrides []ParsedEvent, cfg ExpandConfig) ([]model.Occurrence, bool) {
+// expandRecurringEvent expands an RRULE-based event within
+// [cfg.RangeStart, cfg.RangeEnd].
+//
+// Besides instances starting inside the window, instances that started
+// earlier but are still ongoing at RangeStart are included. The
+// MaxOccurrencesPerEvent cap only counts these in-range instances; for
+// simple FREQ/INTERVAL rules DTSTART is moved close to the window first (see
+// fastForwardStart), so a daily rule created years ago does not walk its
+// whole history on every refresh.
+func expandRecurringEvent(ev ParsedEvent, overrides []ParsedEvent, cfg ExpandConfig) ([]model.Occurrence, bool, error) {
 	out := make([]model.Occurrence, 0)
 	hitCap := false
 
-	// Create base rule from RawRRule.
-	r, err := rrule.StrToRRule(ev.RawRRule)
-	if err != nil {
-		appLog.Error("expand: failed to parse RRULE", err, "uid", ev.UID, "rrule", ev.RawRRule)
-		return out, false
-	}
-
-	// Ensure Dtstart is set to the event's DTSTART.
-	r.DTStart(ev.Start)
-
-	// Build a set so we can apply EXDATE.
-	var set rrule.Set
-	set.RRule(r)
-
-	// Apply EXDATEs.
-	for _, ex := range ev.ExDates {
-		// Best effort: align EXDATE location with event's start.
-		exInLoc := ex.In(ev.Start.Location())
-		set.ExDate(exInLoc)
+	allDaySpan := allDaySpanDays(ev)
+	dur := ev.End.Sub(ev.Start)
+	if dur < 0 {
+		dur = 0
 	}
 
-	// Adjust range into the event's original location for Between().
+	// Adjust range into the event's original location.
 	rangeStart := cfg.RangeStart.In(ev.Start.Location())
 	rangeEnd := cfg.RangeEnd.In(ev.Start.Location())
 
-	occTimes := set.Between(rangeStart, rangeEnd, true)
+	// Instances starting up to one duration before the window may still be
+	// ongoing at rangeStart. All-day spans get an extra day of slack for
+	// 23h/25h DST days.
+	lookBack := dur
+	if ev.AllDay {
+		lookBack = time.Duration(allDaySpan+1) * 24 * time.Hour
+	}
 
-	if len(occTimes) > cfg.MaxOccurrencesPerEvent {
-		occTimes = occTimes[:cfg.MaxOccurrencesPerEvent]
-		hitCap = true
+	set, err := buildWindowedRuleSet(ev.RawRRule, ev.Start, ev.ExDates, rangeStart.Add(-lookBack))
+	if err != nil {
+		appLog.Error("expand: failed to parse RRULE", err, "uid", ev.UID, "rrule", ev.RawRRule)
+		cfg.Diagnostics.Add(Diagnostic{
+			SourceID: ev.Source.ID,
+			UID:      ev.UID,
+			Line:     ev.Line,
+			Severity: SeverityError,
+			Message:  "invalid RRULE " + strconv.Quote(ev.RawRRule) + ": " + err.Error(),
+		})
+		return out, false, err
 	}
 
-	for _, occStart := range occTimes {
+	next := set.Iterator()
+	for {
+		occStart, ok := next()
+		if !ok || occStart.After(rangeEnd) {
+			break
+		}
+
 		var occEnd time.Time
 		if ev.AllDay {
-			// All-day: treat as [date 00:00, next day 00:00) in event's timezone.
+			// All-day: treat as [date 00:00, date+N 00:00) in event's timezone.
+			// Use calendar-day arithmetic so that DST transition days (23h/25h)
+			// still end at the following midnight.
 			date := time.Date(occStart.Year(), occStart.Month(), occStart.Day(), 0, 0, 0, 0, occStart.Location())
 			occStart = date
-			occEnd = date.Add(24 * time.Hour)
+			occEnd = date.AddDate(0, 0, allDaySpan)
 		} else {
 			// Preserve original duration.
-			dur := ev.End.Sub(ev.Start)
 			occEnd = occStart.Add(dur)
 		}
 
+		// Skip instances that are over before the window starts.
+		if occStart.Before(rangeStart) && !occEnd.After(rangeStart) {
+			continue
+		}
+
+		if len(out) >= cfg.MaxOccurrencesPerEvent {
+			hitCap = true
+			break
+		}
+
 		baseStart := occStart
 		baseEnd := occEnd
 		baseEv := ev
@@ -197,10 +289,130 @@ func expandRecurringEvent(ev ParsedEvent, overrides []ParsedEvent, cfg ExpandCon
 			baseEv = o
 		}
 
-		out = append(out, makeOccurrence(baseEv, baseStart, baseEnd, cfg.DisplayLocation))
+		occ := makeOccurrence(baseEv, baseStart, baseEnd, cfg.DisplayLocation)
+		occ.RecurrenceID = occStart.In(cfg.DisplayLocation)
+		out = append(out, occ)
+	}
+
+	return out, hitCap, nil
+}
+
+// buildRuleSet builds an rrule.Set from a raw RRULE value anchored at
+// dtstart, with the given EXDATEs applied.
+//
+// A floating UNTIL is interpreted in dtstart's location, which for floating
+// and all-day values is the display timezone (see ParseConfig.FloatingLocation).
+func buildRuleSet(rawRRule string, dtstart time.Time, exDates []time.Time) (*rrule.Set, error) {
+	return buildWindowedRuleSet(rawRRule, dtstart, exDates, time.Time{})
+}
+
+// buildWindowedRuleSet is buildRuleSet with DTSTART fast-forwarded towards
+// windowStart (see fastForwardStart). A zero windowStart disables the jump.
+func buildWindowedRuleSet(rawRRule string, dtstart time.Time, exDates []time.Time, windowStart time.Time) (*rrule.Set, error) {
+	opt, err := rrule.StrToROptionInLocation(rawRRule, dtstart.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure Dtstart is set to the component's DTSTART.
+	opt.Dtstart = dtstart
+	if !windowStart.IsZero() {
+		opt.Dtstart = fastForwardStart(opt, dtstart, windowStart)
+	}
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build a set so we can apply EXDATE.
+	set := &rrule.Set{}
+	set.RRule(r)
+
+	for _, ex := range exDates {
+		// Best effort: align EXDATE location with the start.
+		set.ExDate(ex.In(dtstart.Location()))
+	}
+	return set, nil
+}
+
+// fastForwardStart returns a new DTSTART that is a whole number of rule
+// periods (FREQ * INTERVAL) after dtstart and no later than windowStart, so
+// that iteration starts close to the window instead of at the original
+// DTSTART. Because the jump is by whole periods, BYxxx parts and the
+// wall-clock time keep producing exactly the same instances.
+//
+// The original dtstart is returned when jumping would change the result:
+//
+//   - COUNT rules (the count starts at the original DTSTART)
+//   - MONTHLY/YEARLY rules starting after the 28th (AddDate would normalize
+//     e.g. Jan 31 + 1 month into March)
+//   - sub-daily frequencies
+func fastForwardStart(opt *rrule.ROption, dtstart, windowStart time.Time) time.Time {
+	if opt.Count > 0 || !windowStart.After(dtstart) {
+		return dtstart
+	}
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	ws := windowStart.In(dtstart.Location())
+
+	var jump func(k int) time.Time
+	var k int
+	switch opt.Freq {
+	case rrule.DAILY, rrule.WEEKLY:
+		period := interval
+		if opt.Freq == rrule.WEEKLY {
+			period *= 7
+		}
+		// One day of slack for DST-shortened days.
+		days := int(ws.Sub(dtstart).Hours()/24) - 1
+		k = days / period
+		jump = func(k int) time.Time { return dtstart.AddDate(0, 0, k*period) }
+	case rrule.MONTHLY:
+		if dtstart.Day() > 28 {
+			return dtstart
+		}
+		months := (ws.Year()-dtstart.Year())*12 + int(ws.Month()) - int(dtstart.Month()) - 1
+		k = months / interval
+		jump = func(k int) time.Time { return dtstart.AddDate(0, k*interval, 0) }
+	case rrule.YEARLY:
+		if dtstart.Day() > 28 {
+			return dtstart
+		}
+		years := ws.Year() - dtstart.Year() - 1
+		k = years / interval
+		jump = func(k int) time.Time { return dtstart.AddDate(k*interval, 0, 0) }
+	default:
+		return dtstart
+	}
+
+	// A jump onto a DST gap day would shift the wall-clock time of every
+	// later instance (02:30 becomes 03:30); step back one period in that case.
+	for ; k > 0; k-- {
+		t := jump(k)
+		if t.Hour() == dtstart.Hour() && t.Minute() == dtstart.Minute() && t.Second() == dtstart.Second() {
+			return t
+		}
 	}
+	return dtstart
+}
 
-	return out, hitCap
+// allDaySpanDays returns the number of calendar days covered by an all-day
+// event (DTEND is exclusive). Missing or non-positive spans count as one day.
+func allDaySpanDays(ev ParsedEvent) int {
+	if ev.End.IsZero() || !ev.End.After(ev.Start) {
+		return 1
+	}
+	loc := ev.Start.Location()
+	end := ev.End.In(loc)
+	startDate := time.Date(ev.Start.Year(), ev.Start.Month(), ev.Start.Day(), 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(endDate.Sub(startDate).Hours() / 24)
+	if days < 1 {
+		return 1
+	}
+	return days
 }
 
 // findOverrideForStart finds an override event whose RECURRENCE-ID matches
@@ -226,13 +438,20 @@ func makeOccurrence(ev ParsedEvent, start, end time.Time, displayLoc *time.Locat
 	endLocal := end.In(displayLoc)
 
 	occ := model.Occurrence{
-		SourceID: ev.Source.ID,
-		UID:      ev.UID,
-		Summary:  ev.Summary,
-		Location: ev.Location,
-		AllDay:   ev.AllDay,
-		Start:    startLocal,
-		End:      endLocal,
+		SourceID:    ev.Source.ID,
+		UID:         ev.UID,
+		Summary:     ev.Summary,
+		Description: ev.Description,
+		Location:    ev.Location,
+		Categories:  ev.Categories,
+		URL:         ev.URL,
+		Color:       ev.Color,
+		Priority:    ev.Priority,
+		Transparent: ev.Transparent,
+		AlarmOffset: ev.AlarmOffset,
+		AllDay:      ev.AllDay,
+		Start:       startLocal,
+		End:         endLocal,
 	}
 
 	// InstanceKey: use start time in RFC3339 as a stable per-instance key.
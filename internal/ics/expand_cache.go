@@ -0,0 +1,148 @@
+package ics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// ExpandCache memoizes ExpandOccurrences results per UID.
+//
+// Entries are keyed by a fingerprint of every field that influences the
+// expansion (base events, overrides, window, display timezone and cap), so a
+// changed event or a moved window simply misses and is re-expanded. Entries
+// not used for ttl are dropped at the start of each ExpandOccurrences call.
+//
+// A nil *ExpandCache is valid and disables caching. It is safe for
+// concurrent use.
+type ExpandCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*expandCacheEntry
+}
+
+type expandCacheEntry struct {
+	occurrences []model.Occurrence
+	truncated   bool
+	lastUsed    time.Time
+}
+
+// NewExpandCache creates an ExpandCache. ttl <= 0 defaults to one hour.
+func NewExpandCache(ttl time.Duration) *ExpandCache {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &ExpandCache{
+		ttl:     ttl,
+		entries: make(map[string]*expandCacheEntry),
+	}
+}
+
+// Len returns the number of cached UID expansions.
+func (c *ExpandCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Invalidate drops every cached expansion.
+func (c *ExpandCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries = make(map[string]*expandCacheEntry)
+	c.mu.Unlock()
+}
+
+// get returns a copy of the cached occurrences for key.
+func (c *ExpandCache) get(key string) ([]model.Occurrence, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+	e.lastUsed = time.Now()
+	// Callers may modify the returned occurrences; hand out a copy.
+	out := make([]model.Occurrence, len(e.occurrences))
+	copy(out, e.occurrences)
+	return out, e.truncated, true
+}
+
+func (c *ExpandCache) put(key string, occs []model.Occurrence, truncated bool) {
+	stored := make([]model.Occurrence, len(occs))
+	copy(stored, occs)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &expandCacheEntry{
+		occurrences: stored,
+		truncated:   truncated,
+		lastUsed:    time.Now(),
+	}
+}
+
+// prune drops entries that have not been used within ttl.
+func (c *ExpandCache) prune(now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if now.Sub(e.lastUsed) > c.ttl {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// expandCacheKey fingerprints one UID group together with the expansion
+// parameters.
+func expandCacheKey(baseEvents, overrides []ParsedEvent, cfg ExpandConfig) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%d\n",
+		cfg.RangeStart.UTC().Format(time.RFC3339Nano),
+		cfg.RangeEnd.UTC().Format(time.RFC3339Nano),
+		cfg.DisplayLocation.String(),
+		cfg.MaxOccurrencesPerEvent,
+	)
+	for _, ev := range baseEvents {
+		writeEventFingerprint(h, "B", ev)
+	}
+	for _, ev := range overrides {
+		writeEventFingerprint(h, "O", ev)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeEventFingerprint(h hash.Hash, kind string, ev ParsedEvent) {
+	fmtTime := func(t time.Time) string {
+		return t.Format(time.RFC3339Nano) + "@" + t.Location().String()
+	}
+	alarm := ""
+	if ev.AlarmOffset != nil {
+		alarm = ev.AlarmOffset.String()
+	}
+	rid := ""
+	if ev.Recurrence != nil {
+		rid = fmtTime(*ev.Recurrence)
+	}
+	fmt.Fprintf(h, "%s|%s|%s|%q|%q|%q|%q|%q|%q|%d|%t|%s|%t|%s|%s|%q|%s|",
+		kind, ev.Source.ID, ev.UID, ev.Summary, ev.Description, ev.Location,
+		ev.Categories, ev.URL, ev.Color, ev.Priority, ev.Transparent, alarm, ev.AllDay,
+		fmtTime(ev.Start), fmtTime(ev.End), ev.RawRRule, rid,
+	)
+	for _, ex := range ev.ExDates {
+		fmt.Fprint(h, fmtTime(ex), ",")
+	}
+	fmt.Fprintln(h)
+}
@@ -0,0 +1,148 @@
+package ics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// longRunningEvents returns n recurring events of the kind that made
+// expansion slow: open-ended rules started years ago (daily standups,
+// weekly 1:1s, monthly reviews) plus a moved instance per series.
+func longRunningEvents(n int, now time.Time) []ParsedEvent {
+	loc := now.Location()
+	rules := []string{"FREQ=DAILY", "FREQ=WEEKLY;BYDAY=MO,WE,FR", "FREQ=WEEKLY;INTERVAL=2", "FREQ=MONTHLY;BYMONTHDAY=15"}
+	events := make([]ParsedEvent, 0, 2*n)
+	for i := range n {
+		start := time.Date(2015+i%8, time.Month(1+i%12), 1+i%28, 9+i%8, 0, 0, 0, loc)
+		base := ParsedEvent{
+			Source:   Source{ID: "bench"},
+			UID:      fmt.Sprintf("series-%d@epdcal", i),
+			Summary:  fmt.Sprintf("Series %d", i),
+			Start:    start,
+			End:      start.Add(30 * time.Minute),
+			RawRRule: rules[i%len(rules)],
+		}
+		moved := base
+		rid := time.Date(now.Year(), now.Month(), now.Day(), start.Hour(), 0, 0, 0, loc)
+		moved.Recurrence = &rid
+		moved.IsOverride = true
+		moved.Start, moved.End = rid.Add(time.Hour), rid.Add(90*time.Minute)
+		events = append(events, base, moved)
+	}
+	return events
+}
+
+// refreshExpandConfig builds the window the way runRefreshCycle does.
+func refreshExpandConfig(now time.Time, cache *ExpandCache) ExpandConfig {
+	start, end := DayWindow(now, 7, 35)
+	return ExpandConfig{
+		DisplayLocation:        now.Location(),
+		RangeStart:             start,
+		RangeEnd:               end,
+		MaxOccurrencesPerEvent: 5000,
+		Cache:                  cache,
+	}
+}
+
+func TestDayWindowStableWithinDay(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	// Spring-forward day: midnight alignment must survive the 23h day.
+	morning := time.Date(2025, 3, 9, 0, 5, 0, 0, loc)
+	evening := time.Date(2025, 3, 9, 23, 55, 0, 0, loc)
+
+	s1, e1 := DayWindow(morning, 7, 35)
+	s2, e2 := DayWindow(evening, 7, 35)
+	if !s1.Equal(s2) || !e1.Equal(e2) {
+		t.Fatalf("window moved within a day: [%s, %s) vs [%s, %s)", s1, e1, s2, e2)
+	}
+	if s1.Hour() != 0 || e1.Hour() != 0 {
+		t.Errorf("window [%s, %s) is not aligned to local midnight", s1, e1)
+	}
+	if want := time.Date(2025, 4, 14, 0, 0, 0, 0, loc); !e1.Equal(want) {
+		t.Errorf("end %s, want %s (end of the 35th day)", e1, want)
+	}
+}
+
+func TestExpandCacheHitsAcrossRefreshes(t *testing.T) {
+	loc := mustLoad(t, "Asia/Seoul")
+	first := time.Date(2025, 6, 2, 12, 0, 0, 0, loc)
+	events := longRunningEvents(20, first)
+	cache := NewExpandCache(time.Hour)
+
+	a, err := ExpandOccurrences(events, refreshExpandConfig(first, cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := cache.Len()
+
+	// A refresh a few minutes later must reuse every entry instead of
+	// adding new ones under a different window key.
+	later := first.Add(7 * time.Minute)
+	for _, uid := range []string{"series-0@epdcal", "series-1@epdcal"} {
+		var base, ov []ParsedEvent
+		for _, ev := range events {
+			if ev.UID != uid {
+				continue
+			}
+			if ev.IsOverride {
+				ov = append(ov, ev)
+			} else {
+				base = append(base, ev)
+			}
+		}
+		if expandCacheKey(base, ov, refreshExpandConfig(first, cache)) != expandCacheKey(base, ov, refreshExpandConfig(later, cache)) {
+			t.Fatalf("%s: cache key changed between refreshes", uid)
+		}
+	}
+	b, err := ExpandOccurrences(events, refreshExpandConfig(later, cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != n {
+		t.Errorf("cache grew from %d to %d entries; second refresh missed", n, cache.Len())
+	}
+	if len(a.Occurrences) != len(b.Occurrences) {
+		t.Errorf("occurrences: %d then %d", len(a.Occurrences), len(b.Occurrences))
+	}
+}
+
+// BenchmarkExpandOccurrences measures a refresh of 500 long-running series
+// without the cache and with a cache warmed by the previous refresh. Each
+// iteration's window is built like runRefreshCycle's, a few minutes after
+// the previous one.
+func BenchmarkExpandOccurrences(b *testing.B) {
+	loc, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		b.Skip(err)
+	}
+	y, m, d := time.Now().In(loc).Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, loc)
+	events := longRunningEvents(500, noon)
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			i++
+			if _, err := ExpandOccurrences(events, refreshExpandConfig(noon.Add(time.Duration(i)*time.Second), nil)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		cache := NewExpandCache(time.Hour)
+		if _, err := ExpandOccurrences(events, refreshExpandConfig(noon, cache)); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			i++
+			if _, err := ExpandOccurrences(events, refreshExpandConfig(noon.Add(time.Duration(i)*time.Second), cache)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,429 @@
+package ics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// ExportOptions controls WriteCalendar, JCal and JSCalendar.
+type ExportOptions struct {
+	// Name is exposed as X-WR-CALNAME (shown by most calendar apps).
+	Name string
+
+	// Location is the zone DATE-TIME values are written in. A VTIMEZONE for
+	// it is emitted unless it is UTC. nil means time.Local.
+	Location *time.Location
+
+	// RangeStart / RangeEnd bound the generated VTIMEZONE transitions. They
+	// default to the span of the exported occurrences.
+	RangeStart time.Time
+	RangeEnd   time.Time
+
+	// Stamp is written as DTSTAMP (RFC 5545 requires it); it should be the
+	// time the data was produced. Zero means now.
+	Stamp time.Time
+}
+
+// exportProdID identifies epdcal in generated calendars.
+const exportProdID = "-//epdcal//export//EN"
+
+// component is a calendar component in the shape shared by the iCalendar
+// text format and jCal (RFC 7265): properties plus sub-components.
+type component struct {
+	name  string
+	props []property
+	subs  []component
+}
+
+// property holds its values in jCal form (e.g. "2026-03-01T09:00:00",
+// "+09:00"); the text writer converts them back to RFC 5545 syntax.
+type property struct {
+	name   string
+	params [][2]string
+	typ    string // jCal value type: text, date, date-time, duration, integer, uri, utc-offset
+	values []string
+}
+
+func textProp(name string, values ...string) property {
+	return property{name: name, typ: "text", values: values}
+}
+
+// WriteCalendar serializes expanded occurrences as an RFC 5545 VCALENDAR.
+//
+// Every occurrence becomes its own plain VEVENT (no RRULE or
+// RECURRENCE-ID) so that the feed shows exactly what the panel shows
+// (rules, dedup and overrides already applied). Single events keep their
+// UID; an instance of a recurring event gets a UID of its own (see
+// exportUID) with the series UID in X-EPDCAL-UID, since instances sharing
+// a UID without their master are not a valid recurrence set. Should two
+// occurrences still map to the same UID (dedup disabled), only the first
+// one is written so that the output stays valid.
+//
+// ParseICS reads the result back to the same UIDs (exportUID for
+// instances), times, all-day flags and properties.
+func WriteCalendar(w io.Writer, occs []model.Occurrence, opts ExportOptions) error {
+	lw := &lineWriter{w: w}
+	writeComponent(lw, buildCalendar(occs, opts))
+	return lw.err
+}
+
+// buildCalendar builds the VCALENDAR shared by WriteCalendar and JCal.
+func buildCalendar(occs []model.Occurrence, opts ExportOptions) component {
+	loc, stamp := exportDefaults(&opts)
+
+	rangeStart, rangeEnd := opts.RangeStart, opts.RangeEnd
+	for _, occ := range occs {
+		if rangeStart.IsZero() || occ.Start.Before(rangeStart) {
+			rangeStart = occ.Start
+		}
+		if rangeEnd.IsZero() || occ.End.After(rangeEnd) {
+			rangeEnd = occ.End
+		}
+	}
+	if rangeStart.IsZero() {
+		rangeStart = stamp
+	}
+	if rangeEnd.Before(rangeStart) {
+		rangeEnd = rangeStart
+	}
+
+	cal := component{name: "vcalendar"}
+	cal.props = append(cal.props,
+		textProp("version", "2.0"),
+		textProp("prodid", exportProdID),
+		textProp("calscale", "GREGORIAN"),
+	)
+	if opts.Name != "" {
+		cal.props = append(cal.props, textProp("x-wr-calname", opts.Name))
+	}
+	if loc != time.UTC {
+		cal.props = append(cal.props, textProp("x-wr-timezone", loc.String()))
+		cal.subs = append(cal.subs, buildVTimezone(loc, rangeStart, rangeEnd))
+	}
+
+	for _, occ := range exportable(occs) {
+		cal.subs = append(cal.subs, buildVEvent(occ, loc, stamp))
+	}
+	return cal
+}
+
+func exportDefaults(opts *ExportOptions) (*time.Location, time.Time) {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.Stamp.IsZero() {
+		opts.Stamp = time.Now()
+	}
+	return opts.Location, opts.Stamp
+}
+
+// exportable returns occs ordered by start and UID with occurrences that
+// map to an already used export UID removed.
+func exportable(occs []model.Occurrence) []model.Occurrence {
+	out := make([]model.Occurrence, 0, len(occs))
+	seen := make(map[string]bool, len(occs))
+	for _, occ := range sortForExport(occs) {
+		uid := exportUID(occ)
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		out = append(out, occ)
+	}
+	return out
+}
+
+// exportUID is the UID an occurrence is exported with: the event UID for
+// single events, and for instances of a recurring event the UID followed
+// by the instance's original start (RECURRENCE-ID) in UTC, or its date for
+// all-day events, e.g. "standup@example.com/20250106T000000Z". It is
+// stable across refreshes as long as the instance is not removed.
+func exportUID(occ model.Occurrence) string {
+	if occ.RecurrenceID.IsZero() {
+		return occ.UID
+	}
+	if occ.AllDay {
+		return occ.UID + "/" + occ.RecurrenceID.Format("20060102")
+	}
+	return occ.UID + "/" + occ.RecurrenceID.UTC().Format("20060102T150405Z")
+}
+
+func buildVEvent(occ model.Occurrence, loc *time.Location, stamp time.Time) component {
+	ev := component{name: "vevent"}
+	add := func(p property) { ev.props = append(ev.props, p) }
+
+	add(textProp("uid", exportUID(occ)))
+	add(property{name: "dtstamp", typ: "date-time", values: []string{stamp.UTC().Format("2006-01-02T15:04:05Z")}})
+	add(timeProp("dtstart", occ.Start, occ.AllDay, loc))
+	add(timeProp("dtend", occ.End, occ.AllDay, loc))
+	add(textProp("summary", occ.Summary))
+	if occ.Description != "" {
+		add(textProp("description", occ.Description))
+	}
+	if occ.Location != "" {
+		add(textProp("location", occ.Location))
+	}
+	if len(occ.Categories) > 0 {
+		add(textProp("categories", occ.Categories...))
+	}
+	if occ.URL != "" {
+		add(property{name: "url", typ: "uri", values: []string{occ.URL}})
+	}
+	if occ.Color != "" {
+		add(textProp("color", occ.Color))
+	}
+	if occ.Priority > 0 {
+		add(property{name: "priority", typ: "integer", values: []string{strconv.Itoa(occ.Priority)}})
+	}
+	if occ.Transparent {
+		add(textProp("transp", "TRANSPARENT"))
+	} else {
+		add(textProp("transp", "OPAQUE"))
+	}
+	if !occ.RecurrenceID.IsZero() {
+		add(textProp("x-epdcal-uid", occ.UID))
+	}
+	if len(occ.SourceIDs) > 0 {
+		add(textProp("x-epdcal-sources", strings.Join(occ.SourceIDs, ",")))
+	} else if occ.SourceID != "" {
+		add(textProp("x-epdcal-sources", occ.SourceID))
+	}
+	if occ.AlarmOffset != nil {
+		ev.subs = append(ev.subs, component{name: "valarm", props: []property{
+			textProp("action", "DISPLAY"),
+			textProp("description", occ.Summary),
+			{name: "trigger", typ: "duration", values: []string{formatDuration(*occ.AlarmOffset)}},
+		}})
+	}
+	return ev
+}
+
+// timeProp renders a DATE (all-day) or DATE-TIME property in loc.
+func timeProp(name string, t time.Time, allDay bool, loc *time.Location) property {
+	t = t.In(loc)
+	switch {
+	case allDay:
+		return property{name: name, typ: "date", values: []string{t.Format("2006-01-02")}}
+	case loc == time.UTC:
+		return property{name: name, typ: "date-time", values: []string{t.Format("2006-01-02T15:04:05Z")}}
+	default:
+		return property{
+			name:   name,
+			params: [][2]string{{"tzid", loc.String()}},
+			typ:    "date-time",
+			values: []string{t.Format("2006-01-02T15:04:05")},
+		}
+	}
+}
+
+// buildVTimezone builds a VTIMEZONE for loc covering [from, to]. Go does
+// not expose zone rules, so observances are derived from the actual
+// transitions in the range (one explicit STANDARD/DAYLIGHT per transition,
+// plus the one in effect at from). Zones without transitions collapse to a
+// single STANDARD observance.
+func buildVTimezone(loc *time.Location, from, to time.Time) component {
+	f := from.In(loc)
+	from = time.Date(f.Year(), f.Month(), f.Day()-1, 0, 0, 0, 0, loc)
+	to = to.In(loc).AddDate(0, 0, 1)
+
+	tz := component{name: "vtimezone", props: []property{textProp("tzid", loc.String())}}
+
+	_, off := from.Zone()
+	tz.subs = append(tz.subs, observance(from, off, off))
+
+	prevOff := off
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if _, o := next.Zone(); o == prevOff {
+			continue
+		}
+		// Binary search the transition instant to the second.
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.Zone(); o == prevOff {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		_, newOff := hi.Zone()
+		tz.subs = append(tz.subs, observance(hi, prevOff, newOff))
+		prevOff = newOff
+	}
+	return tz
+}
+
+// observance builds a STANDARD/DAYLIGHT block starting at instant t.
+// DTSTART is local time in the offset before the change, as RFC 5545
+// requires.
+func observance(t time.Time, fromOff, toOff int) component {
+	kind := "standard"
+	if t.IsDST() {
+		kind = "daylight"
+	}
+	name, _ := t.Zone()
+	onset := t.UTC().Add(time.Duration(fromOff) * time.Second)
+
+	c := component{name: kind, props: []property{
+		{name: "dtstart", typ: "date-time", values: []string{onset.Format("2006-01-02T15:04:05")}},
+		{name: "tzoffsetfrom", typ: "utc-offset", values: []string{formatOffset(fromOff)}},
+		{name: "tzoffsetto", typ: "utc-offset", values: []string{formatOffset(toOff)}},
+	}}
+	if name != "" && !strings.HasPrefix(name, "+") && !strings.HasPrefix(name, "-") {
+		c.props = append(c.props, textProp("tzname", name))
+	}
+	return c
+}
+
+// formatOffset renders a UTC offset in jCal form (+09:00).
+func formatOffset(sec int) string {
+	sign := '+'
+	if sec < 0 {
+		sign = '-'
+		sec = -sec
+	}
+	s := fmt.Sprintf("%c%02d:%02d", sign, sec/3600, sec/60%60)
+	if sec%60 != 0 {
+		s += fmt.Sprintf(":%02d", sec%60)
+	}
+	return s
+}
+
+// formatDuration renders d as an RFC 5545 DURATION (e.g. -PT15M, P1D).
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		b.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+	}
+	if d > 0 || days == 0 {
+		b.WriteByte('T')
+		h, m, s := d/time.Hour, d/time.Minute%60, d/time.Second%60
+		if h > 0 {
+			b.WriteString(strconv.FormatInt(int64(h), 10) + "H")
+		}
+		if m > 0 {
+			b.WriteString(strconv.FormatInt(int64(m), 10) + "M")
+		}
+		if s > 0 || (h == 0 && m == 0) {
+			b.WriteString(strconv.FormatInt(int64(s), 10) + "S")
+		}
+	}
+	return b.String()
+}
+
+// writeComponent writes c in RFC 5545 text form.
+func writeComponent(lw *lineWriter, c component) {
+	name := strings.ToUpper(c.name)
+	lw.line("BEGIN:" + name)
+	for _, p := range c.props {
+		lw.line(propertyLine(p))
+	}
+	for _, sub := range c.subs {
+		writeComponent(lw, sub)
+	}
+	lw.line("END:" + name)
+}
+
+// propertyLine converts a jCal-form property to an unfolded content line.
+func propertyLine(p property) string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(p.name))
+	for _, param := range p.params {
+		b.WriteString(";" + strings.ToUpper(param[0]) + "=" + param[1])
+	}
+	if p.typ == "date" {
+		b.WriteString(";VALUE=DATE")
+	}
+	b.WriteByte(':')
+
+	values := make([]string, len(p.values))
+	for i, v := range p.values {
+		switch p.typ {
+		case "text":
+			values[i] = escapeText(v)
+		case "date", "date-time":
+			values[i] = strings.NewReplacer("-", "", ":", "").Replace(v)
+		case "utc-offset":
+			values[i] = v[:1] + strings.ReplaceAll(v[1:], ":", "")
+		default:
+			values[i] = v
+		}
+	}
+	b.WriteString(strings.Join(values, ","))
+	return b.String()
+}
+
+// escapeText escapes an RFC 5545 TEXT value.
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case ';':
+			b.WriteString(`\;`)
+		case ',':
+			b.WriteString(`\,`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// lineWriter writes CRLF-terminated content lines folded at 75 octets
+// without splitting UTF-8 sequences.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // the leading space counts toward the next line
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
+
+// sortForExport orders occurrences by start, then UID, for stable output.
+func sortForExport(occs []model.Occurrence) []model.Occurrence {
+	out := append([]model.Occurrence(nil), occs...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].UID < out[j].UID
+	})
+	return out
+}
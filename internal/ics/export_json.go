@@ -0,0 +1,187 @@
+package ics
+
+import (
+	"strconv"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// Media types for the JSON calendar formats.
+const (
+	JCalContentType       = "application/calendar+json"
+	JSCalendarContentType = "application/jscalendar+json"
+)
+
+// JCal returns the same VCALENDAR as WriteCalendar in jCal form (RFC 7265),
+// ready for encoding/json.
+func JCal(occs []model.Occurrence, opts ExportOptions) []any {
+	return jcalComponent(buildCalendar(occs, opts))
+}
+
+func jcalComponent(c component) []any {
+	props := make([]any, 0, len(c.props))
+	for _, p := range c.props {
+		params := make(map[string]string, len(p.params))
+		for _, kv := range p.params {
+			params[kv[0]] = kv[1]
+		}
+		prop := []any{p.name, params, p.typ}
+		for _, v := range p.values {
+			if p.typ == "integer" {
+				if n, err := strconv.Atoi(v); err == nil {
+					prop = append(prop, n)
+					continue
+				}
+			}
+			prop = append(prop, v)
+		}
+		props = append(props, prop)
+	}
+	subs := make([]any, 0, len(c.subs))
+	for _, sub := range c.subs {
+		subs = append(subs, jcalComponent(sub))
+	}
+	return []any{c.name, props, subs}
+}
+
+// JSGroup is a JSCalendar (RFC 8984) Group holding the exported events.
+type JSGroup struct {
+	Type    string    `json:"@type"`
+	UID     string    `json:"uid"`
+	ProdID  string    `json:"prodId"`
+	Updated string    `json:"updated"`
+	Title   string    `json:"title,omitempty"`
+	Entries []JSEvent `json:"entries"`
+}
+
+// JSEvent is a JSCalendar Event for one occurrence. Recurring instances
+// keep the series UID and carry recurrenceId (RFC 8984 §4.3.1 allows
+// occurrences to be represented on their own this way).
+type JSEvent struct {
+	Type                 string                `json:"@type"`
+	UID                  string                `json:"uid"`
+	Updated              string                `json:"updated"`
+	Title                string                `json:"title"`
+	Description          string                `json:"description,omitempty"`
+	Start                string                `json:"start"`
+	TimeZone             *string               `json:"timeZone"`
+	Duration             string                `json:"duration"`
+	ShowWithoutTime      bool                  `json:"showWithoutTime,omitempty"`
+	RecurrenceID         string                `json:"recurrenceId,omitempty"`
+	RecurrenceIDTimeZone *string               `json:"recurrenceIdTimeZone,omitempty"`
+	Locations            map[string]JSLocation `json:"locations,omitempty"`
+	Keywords             map[string]bool       `json:"keywords,omitempty"`
+	Links                map[string]JSLink     `json:"links,omitempty"`
+	Color                string                `json:"color,omitempty"`
+	Priority             int                   `json:"priority,omitempty"`
+	FreeBusyStatus       string                `json:"freeBusyStatus"`
+	Alerts               map[string]JSAlert    `json:"alerts,omitempty"`
+}
+
+// JSLocation is a JSCalendar Location.
+type JSLocation struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// JSLink is a JSCalendar Link.
+type JSLink struct {
+	Type string `json:"@type"`
+	Href string `json:"href"`
+}
+
+// JSAlert is a JSCalendar Alert with an OffsetTrigger relative to start.
+type JSAlert struct {
+	Type    string          `json:"@type"`
+	Trigger JSOffsetTrigger `json:"trigger"`
+	Action  string          `json:"action"`
+}
+
+// JSOffsetTrigger is a JSCalendar OffsetTrigger.
+type JSOffsetTrigger struct {
+	Type   string `json:"@type"`
+	Offset string `json:"offset"`
+}
+
+// JSCalendar converts occurrences to a JSCalendar Group (RFC 8984). Times
+// are LocalDateTime values in opts.Location with timeZone set; all-day
+// events are floating (timeZone null) with showWithoutTime, as the RFC
+// recommends.
+func JSCalendar(occs []model.Occurrence, opts ExportOptions) JSGroup {
+	loc, stamp := exportDefaults(&opts)
+	updated := stamp.UTC().Format("2006-01-02T15:04:05Z")
+	tzName := loc.String()
+
+	group := JSGroup{
+		Type:    "Group",
+		UID:     "epdcal-" + strconv.FormatInt(stamp.Unix(), 10),
+		ProdID:  exportProdID,
+		Updated: updated,
+		Title:   opts.Name,
+		Entries: make([]JSEvent, 0, len(occs)),
+	}
+
+	const localDateTime = "2006-01-02T15:04:05"
+	for _, occ := range exportable(occs) {
+		start, end := occ.Start.In(loc), occ.End.In(loc)
+		ev := JSEvent{
+			Type:           "Event",
+			UID:            occ.UID,
+			Updated:        updated,
+			Title:          occ.Summary,
+			Description:    occ.Description,
+			Color:          occ.Color,
+			Priority:       occ.Priority,
+			FreeBusyStatus: "busy",
+		}
+		if occ.Transparent {
+			ev.FreeBusyStatus = "free"
+		}
+
+		if occ.AllDay {
+			startDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+			endDate := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+			days := max(int(endDate.Sub(startDate).Hours()/24), 1)
+			ev.Start = startDate.Format(localDateTime)
+			ev.Duration = "P" + strconv.Itoa(days) + "D"
+			ev.ShowWithoutTime = true
+		} else {
+			ev.Start = start.Format(localDateTime)
+			ev.TimeZone = &tzName
+			ev.Duration = formatDuration(end.Sub(start))
+		}
+
+		if !occ.RecurrenceID.IsZero() {
+			rid := occ.RecurrenceID.In(loc)
+			if occ.AllDay {
+				ev.RecurrenceID = time.Date(rid.Year(), rid.Month(), rid.Day(), 0, 0, 0, 0, time.UTC).Format(localDateTime)
+			} else {
+				ev.RecurrenceID = rid.Format(localDateTime)
+				ev.RecurrenceIDTimeZone = &tzName
+			}
+		}
+
+		if occ.Location != "" {
+			ev.Locations = map[string]JSLocation{"1": {Type: "Location", Name: occ.Location}}
+		}
+		if len(occ.Categories) > 0 {
+			ev.Keywords = make(map[string]bool, len(occ.Categories))
+			for _, c := range occ.Categories {
+				ev.Keywords[c] = true
+			}
+		}
+		if occ.URL != "" {
+			ev.Links = map[string]JSLink{"1": {Type: "Link", Href: occ.URL}}
+		}
+		if occ.AlarmOffset != nil {
+			ev.Alerts = map[string]JSAlert{"1": {
+				Type:    "Alert",
+				Trigger: JSOffsetTrigger{Type: "OffsetTrigger", Offset: formatDuration(*occ.AlarmOffset)},
+				Action:  "display",
+			}}
+		}
+		group.Entries = append(group.Entries, ev)
+	}
+	return group
+}
@@ -0,0 +1,143 @@
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// roundTrip exports occs, parses the result with ParseICS and expands it
+// over [from, to) in loc.
+func roundTrip(t *testing.T, occs []model.Occurrence, loc *time.Location, from, to time.Time) ([]model.Occurrence, []Diagnostic, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteCalendar(&buf, occs, ExportOptions{Name: "epdcal", Location: loc, RangeStart: from, RangeEnd: to, Stamp: from}); err != nil {
+		t.Fatalf("WriteCalendar: %v", err)
+	}
+	diags := &Diagnostics{}
+	src := Source{ID: "export", URL: "file://calendar.ics"}
+	events, err := ParseICS(src, buf.Bytes(), ParseConfig{FloatingLocation: loc, Diagnostics: diags})
+	if err != nil {
+		t.Fatalf("ParseICS: %v\n%s", err, buf.String())
+	}
+	res, err := ExpandOccurrences(events, ExpandConfig{DisplayLocation: loc, RangeStart: from, RangeEnd: to, Diagnostics: diags})
+	if err != nil {
+		t.Fatalf("ExpandOccurrences: %v", err)
+	}
+	return res.Occurrences, diags.Items(), buf.String()
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		fixture, zone string
+		from, to      [3]int
+	}{
+		{"floating_time.ics", "Asia/Seoul", [3]int{2025, 1, 1}, [3]int{2025, 2, 1}},
+		{"allday_dst.ics", "America/New_York", [3]int{2025, 3, 1}, [3]int{2025, 12, 1}},
+	} {
+		t.Run(tt.fixture, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			from := time.Date(tt.from[0], time.Month(tt.from[1]), tt.from[2], 0, 0, 0, 0, loc)
+			to := time.Date(tt.to[0], time.Month(tt.to[1]), tt.to[2], 0, 0, 0, 0, loc)
+
+			var want []model.Occurrence
+			for _, occs := range expandFixture(t, tt.fixture, loc, from, to) {
+				want = append(want, occs...)
+			}
+			got, diags, body := roundTrip(t, want, loc, from, to)
+
+			for _, d := range diags {
+				if d.Severity != SeverityInfo {
+					t.Errorf("diagnostic on re-import: %+v", d)
+				}
+			}
+			if strings.Contains(body, "RECURRENCE-ID") || strings.Contains(body, "RRULE") {
+				t.Errorf("export contains recurrence properties:\n%s", body)
+			}
+
+			byUID := make(map[string]model.Occurrence, len(got))
+			for _, o := range got {
+				if _, dup := byUID[o.UID]; dup {
+					t.Errorf("UID %s exported more than once", o.UID)
+				}
+				byUID[o.UID] = o
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d occurrences back, want %d", len(got), len(want))
+			}
+			for _, w := range want {
+				g, ok := byUID[exportUID(w)]
+				if !ok {
+					t.Errorf("%s (%s): missing after round trip", w.UID, w.Start)
+					continue
+				}
+				if !g.Start.Equal(w.Start) || !g.End.Equal(w.End) || g.AllDay != w.AllDay {
+					t.Errorf("%s: got %s – %s (all-day %v), want %s – %s (all-day %v)",
+						exportUID(w), g.Start, g.End, g.AllDay, w.Start, w.End, w.AllDay)
+				}
+				if g.Summary != w.Summary || g.Description != w.Description || g.Location != w.Location {
+					t.Errorf("%s: text properties changed: %q/%q/%q, want %q/%q/%q",
+						exportUID(w), g.Summary, g.Description, g.Location, w.Summary, w.Description, w.Location)
+				}
+			}
+		})
+	}
+}
+
+func TestExportRoundTripProperties(t *testing.T) {
+	loc := mustLoad(t, "Asia/Seoul")
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, loc)
+	alarm := -15 * time.Minute
+	occs := []model.Occurrence{
+		{
+			UID: "weekly@epdcal", SourceID: "work", SourceIDs: []string{"work", "team"},
+			Summary: "Sync; agenda, notes\\links", Description: "line 1\nline 2", Location: "Room 3, 2F",
+			Categories: []string{"Meeting", "Team"}, URL: "https://example.com/m", Priority: 2,
+			AlarmOffset: &alarm, Start: start, End: start.Add(time.Hour), RecurrenceID: start,
+		},
+		// The next instance, moved by an override: its UID follows the
+		// original start.
+		{
+			UID: "weekly@epdcal", SourceID: "work", Summary: "Sync (moved)",
+			Start: start.AddDate(0, 0, 8), End: start.AddDate(0, 0, 8).Add(time.Hour), RecurrenceID: start.AddDate(0, 0, 7),
+		},
+		{UID: "single@epdcal", SourceID: "home", Summary: strings.Repeat("긴 제목 ", 20), Transparent: true, Start: start.Add(3 * time.Hour), End: start.Add(4 * time.Hour)},
+	}
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+	got, diags, _ := roundTrip(t, occs, loc, from, from.AddDate(0, 1, 0))
+	if len(diags) != 0 {
+		t.Errorf("diagnostics on re-import: %+v", diags)
+	}
+	byUID := make(map[string]model.Occurrence)
+	for _, o := range got {
+		byUID[o.UID] = o
+	}
+
+	first, ok := byUID["weekly@epdcal/20250106T000000Z"]
+	if !ok {
+		t.Fatalf("first instance missing; got %v", byUID)
+	}
+	if first.Summary != occs[0].Summary || first.Description != occs[0].Description || first.Location != occs[0].Location {
+		t.Errorf("text: got %q/%q/%q", first.Summary, first.Description, first.Location)
+	}
+	if strings.Join(first.Categories, ",") != "Meeting,Team" || first.URL != occs[0].URL || first.Priority != 2 {
+		t.Errorf("categories/url/priority: got %v, %q, %d", first.Categories, first.URL, first.Priority)
+	}
+	if first.AlarmOffset == nil || *first.AlarmOffset != alarm {
+		t.Errorf("alarm: got %v, want %s", first.AlarmOffset, alarm)
+	}
+
+	moved, ok := byUID["weekly@epdcal/20250113T000000Z"]
+	if !ok || !moved.Start.Equal(occs[1].Start) {
+		t.Errorf("moved instance: got %+v, want start %s", moved, occs[1].Start)
+	}
+
+	single, ok := byUID["single@epdcal"]
+	if !ok || single.Summary != occs[2].Summary || !single.Transparent {
+		t.Errorf("single event: got %+v", single)
+	}
+}
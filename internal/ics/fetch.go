@@ -10,9 +10,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	appLog "epdcal/internal/log"
+	"epdcal/internal/metrics"
 )
 
 // Source represents a single ICS subscription source.
@@ -24,10 +26,25 @@ type Source struct {
 }
 
 // FetchResult contains the outcome of fetching a single ICS source.
+//
+// The payload is streamed from the response into the on-disk cache and is
+// never held in memory as a whole (feeds can be tens of MB on a 512 MB
+// Pi Zero 2 W); read it with Open.
 type FetchResult struct {
 	Source    Source
-	Body      []byte // ICS payload (either freshly fetched or from cache)
-	FromCache bool   // true if we reused cached body due to 304
+	Path      string // cache file holding the ICS payload (freshly fetched or cached)
+	Hash      string // hex SHA-256 of the payload
+	FromCache bool   // true if we reused cached body (304 or fetch failure)
+}
+
+// Open opens the payload for streaming (e.g. into ParseICSStream).
+func (r FetchResult) Open() (io.ReadCloser, error) {
+	return os.Open(r.Path)
+}
+
+// ReadBody reads the whole payload into memory. Prefer Open for events.
+func (r FetchResult) ReadBody() ([]byte, error) {
+	return os.ReadFile(r.Path)
 }
 
 // cacheEntry holds HTTP cache metadata for a single ICS URL.
@@ -35,6 +52,8 @@ type cacheEntry struct {
 	URL          string    `json:"url"`
 	ETag         string    `json:"etag,omitempty"`
 	LastModified string    `json:"last_modified,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`
+	Size         int64     `json:"size,omitempty"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
@@ -86,7 +105,8 @@ func (f *Fetcher) FetchAll(ctx context.Context, sources []Source) ([]FetchResult
 }
 
 // FetchOne fetches a single ICS source, honoring ETag and Last-Modified.
-// It uses a disk cache under f.cacheDir keyed by a hash of the URL.
+// It uses a disk cache under f.cacheDir keyed by a hash of the URL; a 200
+// response is written straight to that cache while it is hashed.
 func (f *Fetcher) FetchOne(ctx context.Context, src Source) (FetchResult, error) {
 	if src.URL == "" {
 		return FetchResult{}, errors.New("source URL is empty")
@@ -102,33 +122,31 @@ func (f *Fetcher) FetchOne(ctx context.Context, src Source) (FetchResult, error)
 	}
 
 	meta, _ := f.loadCacheMeta(cachePath)
-	cachedBody, _ := f.loadCacheBody(cachePath)
+	cached, hasCached := f.cachedResult(src, cachePath, meta)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
 	if err != nil {
 		return FetchResult{}, err
 	}
 
-	// Conditional headers from cache metadata.
-	if meta.ETag != "" {
+	// Conditional headers from cache metadata (only if the body is still there).
+	if hasCached && meta.ETag != "" {
 		req.Header.Set("If-None-Match", meta.ETag)
 	}
-	if meta.LastModified != "" {
+	if hasCached && meta.LastModified != "" {
 		req.Header.Set("If-Modified-Since", meta.LastModified)
 	}
 
 	appLog.Info("ics fetch start", "id", src.ID, "url", redactURL(src.URL))
 
+	started := time.Now()
 	resp, err := f.client.Do(req)
 	if err != nil {
 		// Network error; if we have a cached body, fall back to it.
-		if len(cachedBody) > 0 {
+		recordFetch(src, started, "error", hasCached)
+		if hasCached {
 			appLog.Error("ics fetch network error, using cached body", err, "id", src.ID, "url", redactURL(src.URL))
-			return FetchResult{
-				Source:    src,
-				Body:      cachedBody,
-				FromCache: true,
-			}, nil
+			return cached, nil
 		}
 		return FetchResult{}, err
 	}
@@ -136,59 +154,59 @@ func (f *Fetcher) FetchOne(ctx context.Context, src Source) (FetchResult, error)
 
 	switch resp.StatusCode {
 	case http.StatusOK:
-		// Fresh content.
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			return FetchResult{}, readErr
-		}
-
+		// Fresh content, streamed into the cache.
 		newMeta := cacheEntry{
 			URL:          src.URL,
 			ETag:         resp.Header.Get("ETag"),
 			LastModified: resp.Header.Get("Last-Modified"),
-			UpdatedAt:    time.Now().UTC(),
 		}
-
-		if err := f.saveCache(cachePath, newMeta, body); err != nil {
-			// Log but still return the freshly fetched body.
-			appLog.Error("ics cache save failed", err, "id", src.ID, "url", redactURL(src.URL))
+		res, saveErr := f.saveCache(cachePath, newMeta, resp.Body)
+		if saveErr != nil {
+			recordFetch(src, started, "error", hasCached)
+			if hasCached {
+				appLog.Error("ics fetch read/cache save failed, using cached body", saveErr, "id", src.ID, "url", redactURL(src.URL))
+				return cached, nil
+			}
+			return FetchResult{}, saveErr
 		}
+		recordFetch(src, started, "200", false)
 
 		appLog.Info("ics fetch success", "id", src.ID, "url", redactURL(src.URL), "status", resp.StatusCode, "from_cache", false)
 
-		return FetchResult{
-			Source:    src,
-			Body:      body,
-			FromCache: false,
-		}, nil
+		res.Source = src
+		return res, nil
 
 	case http.StatusNotModified:
 		// No change; use cached body if available.
-		if len(cachedBody) == 0 {
+		recordFetch(src, started, "304", hasCached)
+		if !hasCached {
 			// 304 but no cached body: treat as error.
 			return FetchResult{}, errors.New("received 304 Not Modified but no cached body available")
 		}
 		appLog.Info("ics fetch not modified; using cache", "id", src.ID, "url", redactURL(src.URL))
-		return FetchResult{
-			Source:    src,
-			Body:      cachedBody,
-			FromCache: true,
-		}, nil
+		return cached, nil
 
 	default:
 		// Non-OK status: if we have cached data, fall back to it.
-		if len(cachedBody) > 0 {
+		recordFetch(src, started, strconv.Itoa(resp.StatusCode), hasCached)
+		if hasCached {
 			appLog.Error("ics fetch non-OK, using cached body", errors.New(resp.Status), "id", src.ID, "url", redactURL(src.URL), "status", resp.StatusCode)
-			return FetchResult{
-				Source:    src,
-				Body:      cachedBody,
-				FromCache: true,
-			}, nil
+			return cached, nil
 		}
 		return FetchResult{}, errors.New(resp.Status)
 	}
 }
 
+// recordFetch updates the Prometheus fetch metrics for src. status is the
+// HTTP status code or "error"; fromCache is set when the cached body is used.
+func recordFetch(src Source, started time.Time, status string, fromCache bool) {
+	metrics.FetchDuration.With(src.ID).Observe(time.Since(started).Seconds())
+	metrics.FetchTotal.With(src.ID, status).Inc()
+	if fromCache {
+		metrics.FetchCacheHits.With(src.ID).Inc()
+	}
+}
+
 func (f *Fetcher) cachePathForURL(url string) (string, error) {
 	if url == "" {
 		return "", errors.New("empty url")
@@ -213,31 +231,99 @@ func (f *Fetcher) loadCacheMeta(cachePath string) (cacheEntry, error) {
 	return meta, nil
 }
 
-func (f *Fetcher) loadCacheBody(cachePath string) ([]byte, error) {
+// cachedResult describes the cached body of cachePath, if there is a
+// non-empty one. The hash comes from meta when it still matches the file
+// size; otherwise (older cache layout) the file is hashed once more.
+func (f *Fetcher) cachedResult(src Source, cachePath string, meta cacheEntry) (FetchResult, bool) {
 	bodyFile := filepath.Join(cachePath, "body.ics")
-	return os.ReadFile(bodyFile)
+	fi, err := os.Stat(bodyFile)
+	if err != nil || fi.Size() == 0 {
+		return FetchResult{}, false
+	}
+	hash := meta.SHA256
+	if hash == "" || meta.Size != fi.Size() {
+		if hash, err = hashFile(bodyFile); err != nil {
+			return FetchResult{}, false
+		}
+	}
+	return FetchResult{Source: src, Path: bodyFile, Hash: hash, FromCache: true}, true
 }
 
-func (f *Fetcher) saveCache(cachePath string, meta cacheEntry, body []byte) error {
+// saveCache streams body into the cache directory, hashing it on the way,
+// then records meta. Both files are replaced atomically so that a reader
+// (or a crash) never sees a half-written body.
+func (f *Fetcher) saveCache(cachePath string, meta cacheEntry, body io.Reader) (FetchResult, error) {
 	metaFile := filepath.Join(cachePath, "meta.json")
 	bodyFile := filepath.Join(cachePath, "body.ics")
 
+	tmp, err := os.CreateTemp(cachePath, "body-*.tmp")
+	if err != nil {
+		return FetchResult{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return FetchResult{}, err
+	}
+
 	// Write body first so meta never points at missing body.
-	if err := os.WriteFile(bodyFile, body, 0o600); err != nil {
-		return err
+	if err := os.Rename(tmp.Name(), bodyFile); err != nil {
+		return FetchResult{}, err
 	}
 
+	meta.SHA256 = hex.EncodeToString(h.Sum(nil))
+	meta.Size = n
 	meta.UpdatedAt = time.Now().UTC()
 	data, err := json.MarshalIndent(&meta, "", "  ")
 	if err != nil {
-		return err
+		return FetchResult{}, err
+	}
+	if err := writeFileAtomic(metaFile, data, 0o600); err != nil {
+		return FetchResult{}, err
 	}
 
-	if err := os.WriteFile(metaFile, data, 0o600); err != nil {
+	return FetchResult{Path: bodyFile, Hash: meta.SHA256}, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
 		return err
 	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
 
-	return nil
+// hashFile returns the hex SHA-256 of the file at path.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // redactURL hides sensitive parts of an ICS URL for logging purposes.
@@ -0,0 +1,71 @@
+package ics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFetchOneStreamsToCache(t *testing.T) {
+	body := readFixture(t, "floating_time.ics")
+	sum := sha256.Sum256(body)
+	wantHash := hex.EncodeToString(sum[:])
+
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(t.TempDir())
+	src := Source{ID: "s", URL: srv.URL + "/cal.ics"}
+
+	check := func(step string, res FetchResult, fromCache bool) {
+		t.Helper()
+		if res.FromCache != fromCache || res.Hash != wantHash {
+			t.Errorf("%s: from_cache=%v hash=%s; want %v %s", step, res.FromCache, res.Hash, fromCache, wantHash)
+		}
+		rc, err := res.Open()
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		defer rc.Close()
+		got, _ := io.ReadAll(rc)
+		if string(got) != string(body) {
+			t.Errorf("%s: cached body differs from the response", step)
+		}
+	}
+
+	res, err := f.FetchOne(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("200", res, false)
+
+	res, err = f.FetchOne(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("304", res, true)
+
+	fail.Store(true)
+	res, err = f.FetchOne(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("502 fallback", res, true)
+}
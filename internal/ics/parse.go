@@ -24,6 +24,19 @@ type ParsedEvent struct {
 	Description string
 	Location    string
 
+	Categories []string
+	URL        string
+	Color      string // RFC 7986 COLOR (CSS3 color name), raw value
+	Priority   int    // 0 = undefined, 1 = highest, 9 = lowest
+
+	// Transparent is true for TRANSP:TRANSPARENT (the event does not block
+	// time, e.g. a reminder or an FYI entry).
+	Transparent bool
+
+	// AlarmOffset is the earliest VALARM trigger relative to DTSTART
+	// (negative = before start). nil if the event has no alarm.
+	AlarmOffset *time.Duration
+
 	Start   time.Time
 	End     time.Time
 	AllDay  bool
@@ -34,16 +47,40 @@ type ParsedEvent struct {
 	ExDates    []time.Time
 	Recurrence *time.Time // RECURRENCE-ID (if present) in event's own timezone
 	IsOverride bool       // true if this VEVENT is an override for a recurring instance
+
+	// Line is the 1-based line of the VEVENT's BEGIN in the source body,
+	// used for diagnostics. 0 if unknown.
+	Line int
+}
+
+// ParseConfig controls how ICS payloads are interpreted.
+type ParseConfig struct {
+	// FloatingLocation is the timezone used for floating DATE-TIME values
+	// (no TZID, no trailing "Z") and for DATE values (all-day events).
+	// This should be the configured display timezone so that "09:00" and
+	// "2025-01-01" mean the same wall-clock/day on the panel regardless of
+	// the host's system zone. If nil, time.Local is used.
+	FloatingLocation *time.Location
+
+	// RangeStart / RangeEnd optionally bound the display horizon. Only
+	// ParseICSStream uses them, to drop events that cannot intersect it.
+	// Leave both zero to keep every event.
+	RangeStart time.Time
+	RangeEnd   time.Time
+
+	// Diagnostics, if non-nil, collects structured parse problems (skipped
+	// events, unknown TZIDs, duplicate UIDs, ...).
+	Diagnostics *Diagnostics
 }
 
 // ParseICS parses a single ICS payload into a list of ParsedEvent.
 //
-//   - It relies on the underlying library's VTIMEZONE/TZID handling to
-//     construct proper time.Time values (with Location set).
+//   - TZID parameters are resolved via the system timezone database.
+//   - Floating times and DATE values are anchored in cfg.FloatingLocation.
 //   - It detects all-day events by inspecting the DTSTART value format.
 //   - It records RRULE/EXDATE/RECURRENCE-ID but does not expand recurrences;
 //     expansion is done in internal/ics/expand.go.
-func ParseICS(src Source, body []byte) ([]ParsedEvent, error) {
+func ParseICS(src Source, body []byte, cfg ParseConfig) ([]ParsedEvent, error) {
 	if len(body) == 0 {
 		return nil, errors.New("empty ICS body")
 	}
@@ -51,28 +88,65 @@ func ParseICS(src Source, body []byte) ([]ParsedEvent, error) {
 	cal, err := ical.ParseCalendar(bytes.NewReader(body))
 	if err != nil {
 		appLog.Error("ics parse failed", err, "id", src.ID, "url", redactURL(src.URL))
+		cfg.Diagnostics.Add(Diagnostic{
+			SourceID: src.ID,
+			Severity: SeverityError,
+			Message:  "calendar parse failed: " + err.Error(),
+		})
 		return nil, err
 	}
 
+	if cfg.FloatingLocation == nil {
+		cfg.FloatingLocation = time.Local
+	}
+
+	lines := componentLines(body, "VEVENT")
+
 	events := make([]ParsedEvent, 0)
 
-	for _, comp := range cal.Events() {
-		ev, perr := parseVEvent(src, comp)
+	for i, comp := range cal.Events() {
+		line := 0
+		if i < len(lines) {
+			line = lines[i]
+		}
+		ev, perr := parseVEvent(src, comp, line, cfg)
 		if perr != nil {
 			// Log and skip this event, but keep parsing others.
 			appLog.Error("ics vevent parse failed", perr, "id", src.ID, "url", redactURL(src.URL))
+			cfg.Diagnostics.Add(Diagnostic{
+				SourceID: src.ID,
+				UID:      ev.UID,
+				Line:     line,
+				Severity: SeverityError,
+				Message:  "event skipped: " + perr.Error(),
+			})
 			continue
 		}
 		events = append(events, ev)
 	}
 
+	if cfg.Diagnostics != nil {
+		reportDuplicateUIDs(events, cfg.Diagnostics)
+	}
+
 	appLog.Info("ics parse completed", "id", src.ID, "url", redactURL(src.URL), "event_count", len(events))
 	return events, nil
 }
 
-func parseVEvent(src Source, ve *ical.VEvent) (ParsedEvent, error) {
+func parseVEvent(src Source, ve *ical.VEvent, line int, cfg ParseConfig) (ParsedEvent, error) {
 	var out ParsedEvent
 	out.Source = src
+	out.Line = line
+	floating := cfg.FloatingLocation
+	warn := func(msg string) {
+		cfg.Diagnostics.Add(Diagnostic{
+			SourceID: src.ID,
+			UID:      out.UID,
+			Line:     line,
+			Severity: SeverityWarning,
+			Message:  msg,
+		})
+	}
 
 	// UID
 	uidProp := ve.GetProperty(ical.ComponentPropertyUniqueId)
@@ -99,95 +173,308 @@ func parseVEvent(src Source, ve *ical.VEvent) (ParsedEvent, error) {
 		out.Location = p.Value
 	}
 
-	// DTSTART / DTEND. We use the library's helpers for timezone logic.
-	// NOTE: GetStartAt/GetEndAt in arran4/golang-ical 리턴 타입이 time.Time 하나이므로
-	// 단일 변수 할당만 사용해야 한다.
-	start, _ := ve.GetStartAt()
-	end, _ := ve.GetEndAt()
+	// CATEGORIES (can appear multiple times, each comma-separated) / URL /
+	// COLOR / PRIORITY
+	for _, p := range ve.GetProperties(ical.ComponentPropertyCategories) {
+		out.Categories = append(out.Categories, splitTextList(p.Value)...)
+	}
+	if p := ve.GetProperty(ical.ComponentPropertyUrl); p != nil {
+		out.URL = strings.TrimSpace(p.Value)
+	}
+	if p := ve.GetProperty(ical.ComponentPropertyColor); p != nil {
+		out.Color = strings.ToLower(strings.TrimSpace(p.Value))
+	}
+	if p := ve.GetProperty(ical.ComponentPropertyPriority); p != nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil && n >= 0 && n <= 9 {
+			out.Priority = n
+		}
+	}
+	if p := ve.GetProperty(ical.ComponentPropertyTransp); p != nil {
+		out.Transparent = strings.EqualFold(strings.TrimSpace(p.Value), "TRANSPARENT")
+	}
 
+	// DTSTART / DTEND. We resolve these ourselves instead of using the
+	// library's GetStartAt/GetEndAt, which interpret floating and DATE
+	// values in time.Local (the host zone) rather than the display zone.
+	dtStartProp := ve.GetProperty(ical.ComponentPropertyDtStart)
+	if dtStartProp == nil {
+		return out, errors.New("missing DTSTART")
+	}
+	start, allDay, err := parsePropertyTime(dtStartProp.Value, dtStartProp.ICalParameters, floating)
+	if err != nil {
+		return out, err
+	}
 	out.Start = start
-	out.End = end
-
-	// Detect all-day: if DTSTART has VALUE=DATE or is in YYYYMMDD form
-	allDay := false
-	if dtStartProp := ve.GetProperty(ical.ComponentPropertyDtStart); dtStartProp != nil {
-		val := dtStartProp.Value
-		// VALUE=DATE or no 'T' in the value -> all-day
-		if params := dtStartProp.ICalParameters; params != nil {
-			if vs, ok := params["VALUE"]; ok && len(vs) > 0 && strings.EqualFold(vs[0], "DATE") {
-				allDay = true
-			}
-		}
-		if !strings.Contains(val, "T") {
-			allDay = true
+	out.StartTZ = firstParam(dtStartProp.ICalParameters, "TZID")
+
+	if dtEndProp := ve.GetProperty(ical.ComponentPropertyDtEnd); dtEndProp != nil {
+		if end, _, err := parsePropertyTime(dtEndProp.Value, dtEndProp.ICalParameters, floating); err == nil {
+			out.End = end
+		} else {
+			warn("invalid DTEND: " + err.Error())
 		}
-		// Capture TZID if present.
-		if params := dtStartProp.ICalParameters; params != nil {
-			if tzs, ok := params["TZID"]; ok && len(tzs) > 0 {
-				out.StartTZ = tzs[0]
-			}
+		out.EndTZ = firstParam(dtEndProp.ICalParameters, "TZID")
+	} else if durProp := ve.GetProperty(ical.ComponentPropertyDuration); durProp != nil {
+		// DURATION instead of DTEND: End = Start + DURATION. Whole days on a
+		// DATE start are calendar days, so P1D still ends at the next local
+		// midnight on a 23h/25h DST day.
+		switch d, err := parseICSDuration(durProp.Value); {
+		case err != nil:
+			warn("invalid DURATION: " + err.Error())
+		case d < 0:
+			warn("negative DURATION")
+		case allDay && d%(24*time.Hour) == 0:
+			out.End = start.AddDate(0, 0, int(d/(24*time.Hour)))
+		default:
+			out.End = start.Add(d)
 		}
+	} else {
+		warn("missing DTEND and DURATION")
 	}
 
-	if dtEndProp := ve.GetProperty(ical.ComponentPropertyDtEnd); dtEndProp != nil {
-		if params := dtEndProp.ICalParameters; params != nil {
-			if tzs, ok := params["TZID"]; ok && len(tzs) > 0 {
-				out.EndTZ = tzs[0]
-			}
+	for _, tzid := range []string{out.StartTZ, out.EndTZ} {
+		if noteUnknownTZID(src, tzid) {
+			warn("unknown TZID " + strconv.Quote(tzid) + "; treated as floating time")
+			break
+		}
+	}
+	if cfg.Diagnostics != nil {
+		if !out.End.IsZero() && out.End.Before(out.Start) {
+			warn("DTEND is before DTSTART")
 		}
 	}
 
 	out.AllDay = allDay
 
+	// VALARM: keep only the earliest trigger, relative to DTSTART.
+	out.AlarmOffset = earliestAlarmOffset(ve, out.Start, out.End, floating)
+
 	// RRULE (we only keep raw string here; expansion will be in expand.go).
 	if rruleProp := ve.GetProperty(ical.ComponentPropertyRrule); rruleProp != nil {
 		out.RawRRule = rruleProp.Value
 	}
 
-	// EXDATE (can appear multiple times)
-	// Use string property name to avoid dependency on constant variants.
-	exProps := ve.GetProperties(ical.ComponentPropertyExdate)
-	for _, p := range exProps {
-		val := p.Value
-		if val == "" {
+	// EXDATE / RECURRENCE-ID
+	out.ExDates = parseExDates(&ve.ComponentBase, floating)
+	if rid, ok := parseRecurrenceID(&ve.ComponentBase, floating); ok {
+		out.Recurrence = &rid
+		out.IsOverride = true
+	}
+
+	return out, nil
+}
+
+// earliestAlarmOffset returns the earliest VALARM TRIGGER of ve as an offset
+// from start. Relative triggers may be RELATED=END; absolute (DATE-TIME)
+// triggers are converted to an offset. Returns nil if there is no usable alarm.
+func earliestAlarmOffset(ve *ical.VEvent, start, end time.Time, floating *time.Location) *time.Duration {
+	var best *time.Duration
+	for _, alarm := range ve.Alarms() {
+		p := alarm.GetProperty(ical.ComponentPropertyTrigger)
+		if p == nil {
+			continue
+		}
+
+		var off time.Duration
+		if strings.EqualFold(firstParam(p.ICalParameters, "VALUE"), "DATE-TIME") {
+			t, _, err := parsePropertyTime(p.Value, p.ICalParameters, floating)
+			if err != nil {
+				continue
+			}
+			off = t.Sub(start)
+		} else {
+			d, err := parseICSDuration(p.Value)
+			if err != nil {
+				continue
+			}
+			off = d
+			if strings.EqualFold(firstParam(p.ICalParameters, "RELATED"), "END") && !end.IsZero() {
+				off += end.Sub(start)
+			}
+		}
+
+		if best == nil || off < *best {
+			best = &off
+		}
+	}
+	return best
+}
+
+// parseICSDuration parses an RFC 5545 DURATION value such as "-PT15M",
+// "P1D", "P1DT2H30M" or "P2W". Days and weeks are treated as exact
+// 24h multiples; parseVEvent turns whole days on all-day events back into
+// calendar days.
+func parseICSDuration(v string) (time.Duration, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if v == "" {
+		return 0, errors.New("empty duration")
+	}
+
+	sign := time.Duration(1)
+	switch v[0] {
+	case '-':
+		sign = -1
+		v = v[1:]
+	case '+':
+		v = v[1:]
+	}
+	if !strings.HasPrefix(v, "P") {
+		return 0, errors.New("invalid duration: missing P")
+	}
+	v = v[1:]
+
+	var total time.Duration
+	inTime := false
+	num := ""
+	for _, c := range v {
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T':
+			inTime = true
+		default:
+			if num == "" {
+				return 0, errors.New("invalid duration")
+			}
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, err
+			}
+			num = ""
+			unit := time.Duration(0)
+			switch {
+			case c == 'W' && !inTime:
+				unit = 7 * 24 * time.Hour
+			case c == 'D' && !inTime:
+				unit = 24 * time.Hour
+			case c == 'H' && inTime:
+				unit = time.Hour
+			case c == 'M' && inTime:
+				unit = time.Minute
+			case c == 'S' && inTime:
+				unit = time.Second
+			default:
+				return 0, errors.New("invalid duration unit")
+			}
+			total += time.Duration(n) * unit
+		}
+	}
+	if num != "" {
+		return 0, errors.New("invalid duration: trailing number")
+	}
+	return sign * total, nil
+}
+
+// splitTextList splits a comma-separated TEXT list value (e.g. CATEGORIES)
+// and trims empty entries. golang-ical already unescapes TEXT values, so an
+// escaped "\," inside a single category cannot be told apart and is split too.
+func splitTextList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// reportDuplicateUIDs records a warning for every UID that has more than one
+// base (non-override) VEVENT within the same source.
+func reportDuplicateUIDs(events []ParsedEvent, diags *Diagnostics) {
+	seen := make(map[string]int)
+	for _, ev := range events {
+		if ev.IsOverride {
+			continue
+		}
+		seen[ev.UID]++
+		if seen[ev.UID] == 2 {
+			diags.Add(Diagnostic{
+				SourceID: ev.Source.ID,
+				UID:      ev.UID,
+				Line:     ev.Line,
+				Severity: SeverityWarning,
+				Message:  "duplicate UID: more than one VEVENT without RECURRENCE-ID",
+			})
+		}
+	}
+}
+
+// parseExDates collects EXDATE values of a component. EXDATE can appear
+// multiple times, each possibly comma-separated, and its TZID/VALUE
+// parameters apply to every value of the property.
+func parseExDates(cb *ical.ComponentBase, floating *time.Location) []time.Time {
+	var out []time.Time
+	for _, p := range cb.GetProperties(ical.ComponentPropertyExdate) {
+		if p.Value == "" {
 			continue
 		}
-		parts := strings.Split(val, ",")
-		for _, part := range parts {
+		for _, part := range strings.Split(p.Value, ",") {
 			part = strings.TrimSpace(part)
 			if part == "" {
 				continue
 			}
-			// This parse is relatively naive; for better correctness we
-			// would inspect VALUE/TZID like above. For now, rely on a
-			// helper that handles basic DATE/DATE-TIME/UTC forms.
-			if t, err := parseICSTime(part); err == nil {
-				out.ExDates = append(out.ExDates, t)
+			if t, _, err := parsePropertyTime(part, p.ICalParameters, floating); err == nil {
+				out = append(out, t)
 			}
 		}
 	}
+	return out
+}
+
+// parseRecurrenceID returns the RECURRENCE-ID of an overridden instance.
+// Use raw property name to avoid constant mismatch.
+func parseRecurrenceID(cb *ical.ComponentBase, floating *time.Location) (time.Time, bool) {
+	ridProp := cb.GetProperty("RECURRENCE-ID")
+	if ridProp == nil {
+		return time.Time{}, false
+	}
+	t, _, err := parsePropertyTime(ridProp.Value, ridProp.ICalParameters, floating)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// parsePropertyTime parses a DATE or DATE-TIME property value using its
+// parameters (TZID, VALUE) and reports whether the value is a DATE.
+//
+//   - "...Z" values are UTC.
+//   - DATE-TIME with TZID is resolved via resolveTZID (IANA or Windows
+//     zone names). Unknown TZIDs are treated as floating so the event is
+//     still shown; callers report them once via noteUnknownTZID.
+//   - Floating DATE-TIME and DATE values are anchored in floating.
+func parsePropertyTime(v string, params map[string][]string, floating *time.Location) (time.Time, bool, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, false, errors.New("empty time value")
+	}
+
+	isDate := !strings.Contains(v, "T")
+	if vt := firstParam(params, "VALUE"); strings.EqualFold(vt, "DATE") {
+		isDate = true
+	}
 
-	// RECURRENCE-ID (overridden instance)
-	// Use raw property name to avoid constant mismatch.
-	if ridProp := ve.GetProperty("RECURRENCE-ID"); ridProp != nil {
-		if t, err := parseICSTime(ridProp.Value); err == nil {
-			out.Recurrence = &t
-			out.IsOverride = true
+	loc := floating
+	if tzid := firstParam(params, "TZID"); tzid != "" && !isDate {
+		if l, ok := resolveTZID(tzid); ok {
+			loc = l
 		}
 	}
 
-	return out, nil
+	t, err := parseICSTime(v, loc)
+	return t, isDate, err
 }
 
 // parseICSTime parses a basic ICS date/date-time string into time.Time.
-// NOTE: This is a simplified helper for EXDATE/RECURRENCE-ID where we do
-// not yet have full parameter context. Expansion logic will handle tz
-// normalization later.
-func parseICSTime(v string) (time.Time, error) {
+// Floating date-times and dates are interpreted in loc.
+func parseICSTime(v string, loc *time.Location) (time.Time, error) {
 	v = strings.TrimSpace(v)
 	if v == "" {
 		return time.Time{}, errors.New("empty time value")
 	}
+	if loc == nil {
+		loc = time.Local
+	}
 
 	// UTC form, e.g., 20250101T090000Z
 	if strings.HasSuffix(v, "Z") {
@@ -195,13 +482,24 @@ func parseICSTime(v string) (time.Time, error) {
 		return time.Parse(layout, v)
 	}
 
-	// Local date-time, e.g., 20250101T090000
+	// Floating date-time, e.g., 20250101T090000
 	if strings.Contains(v, "T") {
 		const layout = "20060102T150405"
-		return time.ParseInLocation(layout, v, time.Local)
+		return time.ParseInLocation(layout, v, loc)
 	}
 
 	// Date-only (all-day), e.g., 20250101
 	const layoutDate = "20060102"
-	return time.ParseInLocation(layoutDate, v, time.Local)
+	return time.ParseInLocation(layoutDate, v, loc)
+}
+
+// firstParam returns the first value of the named property parameter, or "".
+func firstParam(params map[string][]string, name string) string {
+	if params == nil {
+		return ""
+	}
+	if vs, ok := params[name]; ok && len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
 }
@@ -0,0 +1,210 @@
+package ics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+func mustLoad(t testing.TB, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func readFixture(t testing.TB, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// expandFixture parses testdata/name with loc as the floating zone and
+// expands it over [from, to) in the same zone.
+func expandFixture(t *testing.T, name string, loc *time.Location, from, to time.Time) map[string][]model.Occurrence {
+	t.Helper()
+	src := Source{ID: "fixture", URL: "file://" + name}
+	events, err := ParseICS(src, readFixture(t, name), ParseConfig{FloatingLocation: loc})
+	if err != nil {
+		t.Fatalf("ParseICS: %v", err)
+	}
+	res, err := ExpandOccurrences(events, ExpandConfig{DisplayLocation: loc, RangeStart: from, RangeEnd: to})
+	if err != nil {
+		t.Fatalf("ExpandOccurrences: %v", err)
+	}
+	byUID := make(map[string][]model.Occurrence)
+	for _, o := range res.Occurrences {
+		byUID[o.UID] = append(byUID[o.UID], o)
+	}
+	return byUID
+}
+
+func TestFloatingTimeFixture(t *testing.T) {
+	for _, zone := range []string{"Asia/Seoul", "America/New_York"} {
+		t.Run(zone, func(t *testing.T) {
+			loc := mustLoad(t, zone)
+			from := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+			byUID := expandFixture(t, "floating_time.ics", loc, from, from.AddDate(0, 1, 0))
+
+			// 09:00 wall clock in the configured zone, EXDATE on the 8th and
+			// the 9th moved to 10:00 by its RECURRENCE-ID override.
+			want := []time.Time{
+				time.Date(2025, 1, 6, 9, 0, 0, 0, loc),
+				time.Date(2025, 1, 7, 9, 0, 0, 0, loc),
+				time.Date(2025, 1, 9, 10, 0, 0, 0, loc),
+				time.Date(2025, 1, 10, 9, 0, 0, 0, loc),
+			}
+			got := byUID["floating-standup@epdcal"]
+			if len(got) != len(want) {
+				t.Fatalf("standup: got %d occurrences, want %d: %v", len(got), len(want), got)
+			}
+			for i, o := range got {
+				if !o.Start.Equal(want[i]) {
+					t.Errorf("standup[%d]: start %s, want %s", i, o.Start, want[i])
+				}
+				if o.Start.Location() != loc {
+					t.Errorf("standup[%d]: start in %s, want %s", i, o.Start.Location(), loc)
+				}
+				if d := o.End.Sub(o.Start); d != 30*time.Minute {
+					t.Errorf("standup[%d]: duration %s, want 30m", i, d)
+				}
+			}
+
+			holiday := byUID["allday-holiday@epdcal"]
+			if len(holiday) != 1 {
+				t.Fatalf("holiday: got %d occurrences, want 1", len(holiday))
+			}
+			if h := holiday[0]; !h.AllDay ||
+				!h.Start.Equal(time.Date(2025, 1, 10, 0, 0, 0, 0, loc)) ||
+				!h.End.Equal(time.Date(2025, 1, 11, 0, 0, 0, 0, loc)) {
+				t.Errorf("holiday: got %s – %s (all-day %v), want local midnight to midnight", h.Start, h.End, h.AllDay)
+			}
+
+			// A UTC value is an absolute instant, whatever the floating zone.
+			call := byUID["utc-call@epdcal"]
+			if len(call) != 1 || !call[0].Start.Equal(time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("utc call: got %v, want 2025-01-07T00:00Z", call)
+			}
+		})
+	}
+}
+
+func TestAllDayDSTFixture(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, loc)
+	byUID := expandFixture(t, "allday_dst.ics", loc, from, time.Date(2025, 12, 1, 0, 0, 0, 0, loc))
+
+	check := func(uid string, days int, want ...time.Time) {
+		t.Helper()
+		got := byUID[uid]
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d occurrences, want %d: %v", uid, len(got), len(want), got)
+		}
+		for i, o := range got {
+			wantEnd := want[i].AddDate(0, 0, days)
+			if !o.AllDay {
+				t.Errorf("%s[%d]: not all-day", uid, i)
+			}
+			if !o.Start.Equal(want[i]) || !o.End.Equal(wantEnd) {
+				t.Errorf("%s[%d]: got %s – %s, want %s – %s", uid, i, o.Start, o.End, want[i], wantEnd)
+			}
+			if o.End.Hour() != 0 || o.End.Minute() != 0 {
+				t.Errorf("%s[%d]: end %s is not local midnight", uid, i, o.End)
+			}
+		}
+	}
+
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, loc) }
+
+	// Spring forward on 2025-03-09: the 23h day must still end at midnight.
+	check("daily-allday-dst@epdcal", 1, day(3, 7), day(3, 8), day(3, 9), day(3, 11))
+	// Fall back on 2025-11-02 inside the second two-day block (49h long).
+	check("weekly-two-day-dst@epdcal", 2, day(10, 25), day(11, 1))
+}
+
+func TestResolveTZID(t *testing.T) {
+	seoul := mustLoad(t, "Asia/Seoul")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	for tzid, want := range map[string]*time.Location{
+		"Asia/Seoul":                            seoul,
+		"Korea Standard Time":                   seoul,
+		"W. Europe Standard Time":               berlin,
+		"/mozilla.org/20050126_1/Europe/Berlin": berlin,
+	} {
+		got, ok := resolveTZID(tzid)
+		if !ok || got.String() != want.String() {
+			t.Errorf("resolveTZID(%q) = %v, %v; want %s", tzid, got, ok, want)
+		}
+	}
+	if _, ok := resolveTZID("Nowhere Standard Time"); ok {
+		t.Error(`resolveTZID("Nowhere Standard Time") resolved`)
+	}
+
+	// Outlook-style TZID lands in Seoul, an unknown one stays floating.
+	ts, _, err := parsePropertyTime("20250106T090000", map[string][]string{"TZID": {"Korea Standard Time"}}, time.UTC)
+	if err != nil || !ts.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Korea Standard Time: got %s, %v", ts, err)
+	}
+	ts, _, err = parsePropertyTime("20250106T090000", map[string][]string{"TZID": {"Nowhere Standard Time"}}, seoul)
+	if err != nil || !ts.Equal(time.Date(2025, 1, 6, 9, 0, 0, 0, seoul)) {
+		t.Errorf("unknown TZID: got %s, %v; want floating 09:00 in Seoul", ts, err)
+	}
+}
+
+func TestDurationFixture(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, loc)
+
+	var diags Diagnostics
+	src := Source{ID: "fixture", URL: "file://duration.ics"}
+	events, err := ParseICS(src, readFixture(t, "duration.ics"), ParseConfig{FloatingLocation: loc, Diagnostics: &diags})
+	if err != nil {
+		t.Fatalf("ParseICS: %v", err)
+	}
+	for _, ev := range events {
+		if ev.UID == "duration-invalid@epdcal" && !ev.End.IsZero() {
+			t.Errorf("invalid DURATION set End to %s", ev.End)
+		}
+	}
+	var warnings []string
+	for _, d := range diags.Items() {
+		warnings = append(warnings, d.UID+": "+d.Message)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "duration-invalid@epdcal: invalid DURATION") {
+		t.Errorf("warnings = %q, want only the invalid DURATION", warnings)
+	}
+
+	byUID := expandFixture(t, "duration.ics", loc, from, from.AddDate(0, 1, 0))
+
+	review := byUID["duration-review@epdcal"]
+	if len(review) != 2 {
+		t.Fatalf("review: got %d occurrences, want 2", len(review))
+	}
+	for i, o := range review {
+		if d := o.End.Sub(o.Start); d != 90*time.Minute {
+			t.Errorf("review[%d]: duration %s, want 1h30m", i, d)
+		}
+	}
+
+	// P2D from a DATE start covers two calendar days, the first of which
+	// is only 23h long.
+	trip := byUID["duration-trip@epdcal"]
+	if len(trip) != 1 {
+		t.Fatalf("trip: got %d occurrences, want 1", len(trip))
+	}
+	if o := trip[0]; !o.AllDay ||
+		!o.Start.Equal(time.Date(2025, 3, 8, 0, 0, 0, 0, loc)) ||
+		!o.End.Equal(time.Date(2025, 3, 10, 0, 0, 0, 0, loc)) {
+		t.Errorf("trip: got %s – %s (all-day %v), want 03-08 to 03-10 local midnight", o.Start, o.End, o.AllDay)
+	}
+}
@@ -0,0 +1,263 @@
+package ics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	appLog "epdcal/internal/log"
+	"epdcal/internal/model"
+)
+
+// maxSourceCacheWindows bounds how many distinct expansion windows are kept
+// per source body (e.g. the panel view plus a few Web UI ranges).
+const maxSourceCacheWindows = 8
+
+// SourceCache keeps the parse + expand result of each ICS source keyed by
+// the SHA-256 of the source body (FetchResult.Hash) together with the
+// expansion window, display timezone and cap, along with the diagnostics
+// that run produced and the source's parsed VTODOs.
+//
+// When a feed is unchanged (typically FetchResult.FromCache after a 304)
+// and the relevant config is the same, Expand and Tasks return the stored
+// result without reading the body again. A new body for a source drops
+// every entry of its previous body; a changed URL, timezone or window
+// simply produces a different key, so callers should build the window with
+// DayWindow rather than from the exact current time.
+//
+// It is safe for concurrent use.
+type SourceCache struct {
+	mu      sync.Mutex
+	entries map[string]*sourceCacheEntry // by source ID
+}
+
+type sourceCacheEntry struct {
+	bodyHash string
+	windows  map[string]*sourceCacheWindow
+	tasks    map[string]sourceCacheTasks // by floating location
+}
+
+type sourceCacheTasks struct {
+	tasks []ParsedTask
+	diags []Diagnostic
+}
+
+type sourceCacheWindow struct {
+	result   ExpandResult
+	diags    []Diagnostic
+	lastUsed time.Time
+}
+
+// NewSourceCache creates an empty SourceCache.
+func NewSourceCache() *SourceCache {
+	return &SourceCache{entries: make(map[string]*sourceCacheEntry)}
+}
+
+// Expand parses and expands a fetched source within cfg's window, or returns
+// the cached result for the same body and parameters. Floating and all-day
+// values are anchored in cfg.DisplayLocation.
+//
+// The returned bool reports a cache hit. Diagnostics of the parse and
+// expand stages are added to cfg.Diagnostics on a hit as well, replayed
+// from the run that filled the cache.
+func (c *SourceCache) Expand(res FetchResult, cfg ExpandConfig) (ExpandResult, bool, error) {
+	if cfg.DisplayLocation == nil {
+		cfg.DisplayLocation = time.Local
+	}
+	if cfg.MaxOccurrencesPerEvent <= 0 {
+		cfg.MaxOccurrencesPerEvent = defaultMaxOccurrencesPerEvent
+	}
+
+	bodyHash, err := res.bodyHash()
+	if err != nil {
+		return ExpandResult{}, false, err
+	}
+	windowKey := fmt.Sprintf("%s|%s|%s|%s|%d",
+		res.Source.URL,
+		cfg.RangeStart.UTC().Format(time.RFC3339Nano),
+		cfg.RangeEnd.UTC().Format(time.RFC3339Nano),
+		cfg.DisplayLocation.String(),
+		cfg.MaxOccurrencesPerEvent,
+	)
+
+	if c != nil {
+		if result, diags, ok := c.lookup(res.Source.ID, bodyHash, windowKey); ok {
+			for _, d := range diags {
+				cfg.Diagnostics.Add(d)
+			}
+			return result, true, nil
+		}
+	}
+
+	// Collect this run's diagnostics separately so they can be stored.
+	diags := &Diagnostics{}
+	out := cfg.Diagnostics
+	cfg.Diagnostics = diags
+	defer func() {
+		for _, d := range diags.Items() {
+			out.Add(d)
+		}
+	}()
+
+	body, err := res.Open()
+	if err != nil {
+		return ExpandResult{}, false, err
+	}
+	events, err := ParseICSStream(res.Source, body, ParseConfig{
+		FloatingLocation: cfg.DisplayLocation,
+		RangeStart:       cfg.RangeStart,
+		RangeEnd:         cfg.RangeEnd,
+		Diagnostics:      diags,
+	})
+	body.Close()
+	if err != nil {
+		return ExpandResult{}, false, err
+	}
+	result, err := ExpandOccurrences(events, cfg)
+	if err != nil {
+		return ExpandResult{}, false, err
+	}
+
+	if c != nil {
+		c.store(res.Source.ID, bodyHash, windowKey, result, diags.Items())
+	}
+	return result, false, nil
+}
+
+// Tasks returns the parsed VTODOs of a fetched source, reading the body
+// (streamed, see ParseTasksStream) only when it changed. Floating and DATE
+// values are anchored in loc. Task parse problems go to diags, replayed on
+// a cache hit like in Expand.
+func (c *SourceCache) Tasks(res FetchResult, loc *time.Location, diags *Diagnostics) ([]ParsedTask, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	bodyHash, err := res.bodyHash()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		c.mu.Lock()
+		e, ok := c.entries[res.Source.ID]
+		if ok && e.bodyHash == bodyHash {
+			if cached, ok := e.tasks[loc.String()]; ok {
+				c.mu.Unlock()
+				for _, d := range cached.diags {
+					diags.Add(d)
+				}
+				return cached.tasks, nil
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	body, err := res.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	own := &Diagnostics{}
+	tasks, err := ParseTasksStream(res.Source, body, ParseConfig{FloatingLocation: loc, Diagnostics: own})
+	for _, d := range own.Items() {
+		diags.Add(d)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil {
+		c.mu.Lock()
+		e := c.entryLocked(res.Source.ID, bodyHash)
+		e.tasks[loc.String()] = sourceCacheTasks{tasks: tasks, diags: own.Items()}
+		c.mu.Unlock()
+	}
+	return tasks, nil
+}
+
+// bodyHash returns r.Hash, hashing the payload file if it is not set.
+func (r FetchResult) bodyHash() (string, error) {
+	if r.Hash != "" {
+		return r.Hash, nil
+	}
+	return hashFile(r.Path)
+}
+
+// Invalidate drops every cached result. Call it after config changes that
+// are not part of the key (e.g. source IDs being reassigned).
+func (c *SourceCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries = make(map[string]*sourceCacheEntry)
+	c.mu.Unlock()
+}
+
+func (c *SourceCache) lookup(sourceID, bodyHash, windowKey string) (ExpandResult, []Diagnostic, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[sourceID]
+	if !ok || e.bodyHash != bodyHash {
+		return ExpandResult{}, nil, false
+	}
+	w, ok := e.windows[windowKey]
+	if !ok {
+		return ExpandResult{}, nil, false
+	}
+	w.lastUsed = time.Now()
+	return copyExpandResult(w.result), w.diags, true
+}
+
+// entryLocked returns the entry of sourceID for bodyHash, replacing an
+// entry of a previous body. c.mu must be held.
+func (c *SourceCache) entryLocked(sourceID, bodyHash string) *sourceCacheEntry {
+	e, ok := c.entries[sourceID]
+	if !ok || e.bodyHash != bodyHash {
+		if ok {
+			appLog.Info("ics source content changed; dropping cached expansions", "id", sourceID)
+		}
+		e = &sourceCacheEntry{
+			bodyHash: bodyHash,
+			windows:  make(map[string]*sourceCacheWindow),
+			tasks:    make(map[string]sourceCacheTasks),
+		}
+		c.entries[sourceID] = e
+	}
+	return e
+}
+
+func (c *SourceCache) store(sourceID, bodyHash, windowKey string, result ExpandResult, diags []Diagnostic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.entryLocked(sourceID, bodyHash)
+	if _, exists := e.windows[windowKey]; !exists && len(e.windows) >= maxSourceCacheWindows {
+		var oldestKey string
+		var oldest time.Time
+		for k, w := range e.windows {
+			if oldestKey == "" || w.lastUsed.Before(oldest) {
+				oldestKey, oldest = k, w.lastUsed
+			}
+		}
+		delete(e.windows, oldestKey)
+	}
+	e.windows[windowKey] = &sourceCacheWindow{
+		result:   copyExpandResult(result),
+		diags:    diags,
+		lastUsed: time.Now(),
+	}
+}
+
+// copyExpandResult copies the slices of r so that callers may modify the
+// returned occurrences without affecting the cache.
+func copyExpandResult(r ExpandResult) ExpandResult {
+	out := ExpandResult{
+		Occurrences: make([]model.Occurrence, len(r.Occurrences)),
+	}
+	copy(out.Occurrences, r.Occurrences)
+	if r.TruncatedEvents != nil {
+		out.TruncatedEvents = append([]string(nil), r.TruncatedEvents...)
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package ics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSourceCacheReplaysDiagnostics(t *testing.T) {
+	loc := mustLoad(t, "Asia/Seoul")
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nUID:ok@epdcal\r\nDTSTART:20250106T090000\r\nDTEND:20250106T100000\r\nSUMMARY:OK\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:broken@epdcal\r\nSUMMARY:No start\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\nUID:todo@epdcal\r\nSUMMARY:Task\r\nDUE;VALUE=DATE:20250107\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+	path := filepath.Join(t.TempDir(), "body.ics")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res := FetchResult{Source: Source{ID: "s1", URL: "https://example.com/a.ics"}, Path: path}
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+	cache := NewSourceCache()
+	run := func() (int, int, bool, int) {
+		t.Helper()
+		diags := &Diagnostics{}
+		cfg := ExpandConfig{DisplayLocation: loc, RangeStart: from, RangeEnd: from.AddDate(0, 1, 0), Diagnostics: diags}
+		result, hit, err := cache.Expand(res, cfg)
+		if err != nil {
+			t.Fatalf("Expand: %v", err)
+		}
+		tasks, err := cache.Tasks(res, loc, diags)
+		if err != nil {
+			t.Fatalf("Tasks: %v", err)
+		}
+		return len(result.Occurrences), len(diags.Items()), hit, len(tasks)
+	}
+
+	occ1, diag1, hit1, tasks1 := run()
+	// 캐시를 채운 뒤 body 가 사라져도 같은 hash 면 다시 읽지 않아야 한다.
+	res.Hash, _ = hashFile(path)
+	os.Remove(path)
+	occ2, diag2, hit2, tasks2 := run()
+
+	if hit1 || !hit2 {
+		t.Errorf("hits: first %v, second %v; want false, true", hit1, hit2)
+	}
+	if occ1 != 1 || occ2 != 1 || tasks1 != 1 || tasks2 != 1 {
+		t.Errorf("got %d/%d occurrences and %d/%d tasks, want 1 each", occ1, occ2, tasks1, tasks2)
+	}
+	if diag1 == 0 || diag2 != diag1 {
+		t.Errorf("diagnostics: first run %d, cached run %d; want the same non-zero count", diag1, diag2)
+	}
+}
@@ -0,0 +1,325 @@
+package ics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	ical "github.com/arran4/golang-ical"
+
+	appLog "epdcal/internal/log"
+	"epdcal/internal/metrics"
+)
+
+// ParseICSStream parses VEVENTs from r one component at a time and keeps
+// only events that can intersect [cfg.RangeStart, cfg.RangeEnd]:
+//
+//   - non-recurring events overlapping the window
+//   - recurring events starting before the window end whose UNTIL/COUNT
+//     can still reach the window start
+//   - RECURRENCE-ID overrides whose original or new time touches the window
+//
+// Unlike ParseICS it never builds the full golang-ical tree, so peak memory
+// is bounded by the largest single VEVENT rather than the whole feed. This
+// matters for 20–40 MB feeds on a 512 MB Pi Zero 2 W.
+//
+// The resulting ParsedEvents are identical to ParseICS output for the kept
+// events. If the window is unset (both zero), every event is kept.
+func ParseICSStream(src Source, r io.Reader, cfg ParseConfig) ([]ParsedEvent, error) {
+	if cfg.FloatingLocation == nil {
+		cfg.FloatingLocation = time.Local
+	}
+
+	var (
+		events   = make([]ParsedEvent, 0)
+		scanned  int
+		filtered int
+	)
+
+	unterminated, err := scanComponents(r, "VEVENT", func(cal *ical.Calendar, begin int, perr error) {
+		scanned++
+		var (
+			ev   ParsedEvent
+			keep bool
+		)
+		if perr == nil {
+			ev, keep, perr = parseStreamedVEvent(src, cal, begin, cfg)
+		}
+		switch {
+		case perr != nil:
+			metrics.ParseErrors.With(src.ID).Inc()
+			appLog.Error("ics vevent parse failed", perr, "id", src.ID, "url", redactURL(src.URL))
+			cfg.Diagnostics.Add(Diagnostic{
+				SourceID: src.ID,
+				UID:      ev.UID,
+				Line:     begin,
+				Severity: SeverityError,
+				Message:  "event skipped: " + perr.Error(),
+			})
+		case keep:
+			events = append(events, ev)
+		default:
+			filtered++
+		}
+	})
+	if err != nil {
+		metrics.ParseErrors.With(src.ID).Inc()
+		appLog.Error("ics parse failed", err, "id", src.ID, "url", redactURL(src.URL))
+		cfg.Diagnostics.Add(Diagnostic{
+			SourceID: src.ID,
+			Severity: SeverityError,
+			Message:  "calendar parse failed: " + err.Error(),
+		})
+		return nil, err
+	}
+	if unterminated > 0 {
+		metrics.ParseErrors.With(src.ID).Inc()
+		cfg.Diagnostics.Add(Diagnostic{
+			SourceID: src.ID,
+			Line:     unterminated,
+			Severity: SeverityError,
+			Message:  "event skipped: unterminated VEVENT",
+		})
+	}
+
+	if cfg.Diagnostics != nil {
+		reportDuplicateUIDs(events, cfg.Diagnostics)
+	}
+
+	metrics.ParseEvents.With(src.ID).Set(float64(len(events)))
+	appLog.Info("ics stream parse completed",
+		"id", src.ID,
+		"url", redactURL(src.URL),
+		"scanned", scanned,
+		"event_count", len(events),
+		"filtered", filtered,
+	)
+	return events, nil
+}
+
+// ParseTasksStream is ParseTasks reading VTODOs from r one component at a
+// time, like ParseICSStream. Every task is kept; ExpandTasks applies the
+// window.
+func ParseTasksStream(src Source, r io.Reader, cfg ParseConfig) ([]ParsedTask, error) {
+	if cfg.FloatingLocation == nil {
+		cfg.FloatingLocation = time.Local
+	}
+
+	tasks := make([]ParsedTask, 0)
+	unterminated, err := scanComponents(r, "VTODO", func(cal *ical.Calendar, begin int, perr error) {
+		var t ParsedTask
+		if perr == nil {
+			if todos := cal.Todos(); len(todos) == 1 {
+				t, perr = parseVTodo(src, todos[0], cfg.FloatingLocation)
+			} else {
+				perr = errors.New("malformed VTODO")
+			}
+		}
+		if perr != nil {
+			appLog.Error("ics vtodo parse failed", perr, "id", src.ID, "url", redactURL(src.URL))
+			cfg.Diagnostics.Add(Diagnostic{
+				SourceID: src.ID,
+				UID:      t.UID,
+				Line:     begin,
+				Severity: SeverityError,
+				Message:  "task skipped: " + perr.Error(),
+			})
+			return
+		}
+		tasks = append(tasks, t)
+	})
+	if err != nil {
+		appLog.Error("ics task parse failed", err, "id", src.ID, "url", redactURL(src.URL))
+		return nil, err
+	}
+	if unterminated > 0 {
+		cfg.Diagnostics.Add(Diagnostic{
+			SourceID: src.ID,
+			Line:     unterminated,
+			Severity: SeverityError,
+			Message:  "task skipped: unterminated VTODO",
+		})
+	}
+
+	appLog.Info("ics task parse completed", "id", src.ID, "url", redactURL(src.URL), "task_count", len(tasks))
+	return tasks, nil
+}
+
+// scanComponents reads r line by line and calls fn for every top-level
+// component called name (nested components such as VALARM included),
+// parsed on its own inside a minimal VCALENDAR, with the line of its
+// BEGIN. If golang-ical rejects the component, fn gets the error instead.
+//
+// It returns the BEGIN line of a trailing component that was never closed
+// (0 if none), or an error if r cannot be read or is not a VCALENDAR.
+func scanComponents(r io.Reader, name string, fn func(cal *ical.Calendar, begin int, err error)) (int, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	beginComp := []byte("BEGIN:" + name)
+	endComp := []byte("END:" + name)
+
+	var (
+		er     componentReader
+		buf    bytes.Buffer
+		inComp bool
+		depth  int // nested components inside the current one (e.g. VALARM)
+		begin  int // line of the current component's BEGIN
+		lineNo int
+		sawCal bool
+	)
+
+	for {
+		raw, err := readLine(br)
+		if len(raw) > 0 {
+			lineNo++
+			line := bytes.TrimRight(raw, "\r\n")
+
+			switch {
+			case !inComp && bytes.EqualFold(line, beginVCalendar):
+				sawCal = true
+			case !inComp && bytes.EqualFold(line, beginComp):
+				inComp = true
+				depth = 0
+				begin = lineNo
+				buf.Reset()
+				buf.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//epdcal//stream//EN\r\n")
+				buf.Write(line)
+				buf.WriteString("\r\n")
+			case inComp:
+				buf.Write(line)
+				buf.WriteString("\r\n")
+				switch {
+				case hasPrefixFold(line, "BEGIN:"):
+					depth++
+				case depth == 0 && bytes.EqualFold(line, endComp):
+					inComp = false
+					buf.WriteString("END:VCALENDAR\r\n")
+					cal, perr := ical.ParseCalendar(er.reset(buf.Bytes()))
+					fn(cal, begin, perr)
+				case hasPrefixFold(line, "END:"):
+					depth--
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if !sawCal {
+		return 0, errors.New("missing BEGIN:VCALENDAR")
+	}
+	if inComp {
+		return begin, nil
+	}
+	return 0, nil
+}
+
+var beginVCalendar = []byte("BEGIN:VCALENDAR")
+
+// readLine returns the next line of br including its newline. The slice
+// aliases br's buffer (valid until the next read) unless the line is longer
+// than the buffer, so ordinary lines are not copied.
+func readLine(br *bufio.Reader) ([]byte, error) {
+	line, err := br.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return line, err
+	}
+	long := append([]byte(nil), line...)
+	for err == bufio.ErrBufferFull {
+		line, err = br.ReadSlice('\n')
+		long = append(long, line...)
+	}
+	return long, err
+}
+
+// hasPrefixFold is a case-insensitive bytes.HasPrefix for ASCII prefixes.
+func hasPrefixFold(b []byte, prefix string) bool {
+	return len(b) >= len(prefix) && bytes.EqualFold(b[:len(prefix)], []byte(prefix))
+}
+
+// componentReader feeds one buffered component at a time to golang-ical.
+// It hands over a *bufio.Reader, which the library uses as is instead of
+// allocating a new 4 KB buffer per component.
+type componentReader struct {
+	rd bytes.Reader
+	br *bufio.Reader
+}
+
+func (c *componentReader) reset(data []byte) *bufio.Reader {
+	c.rd.Reset(data)
+	if c.br == nil {
+		c.br = bufio.NewReader(&c.rd)
+	} else {
+		c.br.Reset(&c.rd)
+	}
+	return c.br
+}
+
+// parseStreamedVEvent parses the single VEVENT of cal and reports whether
+// it can intersect the configured window.
+func parseStreamedVEvent(src Source, cal *ical.Calendar, line int, cfg ParseConfig) (ParsedEvent, bool, error) {
+	vevents := cal.Events()
+	if len(vevents) != 1 {
+		return ParsedEvent{}, false, errors.New("malformed VEVENT")
+	}
+	ev, err := parseVEvent(src, vevents[0], line, cfg)
+	if err != nil {
+		return ev, false, err
+	}
+	return ev, eventCanIntersect(ev, cfg.RangeStart, cfg.RangeEnd), nil
+}
+
+// eventCanIntersect reports whether ev can produce (or affect) an occurrence
+// within [rangeStart, rangeEnd]. It errs on the side of keeping events.
+func eventCanIntersect(ev ParsedEvent, rangeStart, rangeEnd time.Time) bool {
+	if rangeStart.IsZero() && rangeEnd.IsZero() {
+		return true
+	}
+
+	end := ev.End
+	if end.Before(ev.Start) {
+		end = ev.Start
+	}
+
+	if ev.IsOverride && ev.Recurrence != nil {
+		// Keep overrides that move an instance into the window as well as
+		// ones that move an instance out of it (so the base one is replaced).
+		rid := *ev.Recurrence
+		if !rid.Before(rangeStart.AddDate(0, 0, -1)) && !rid.After(rangeEnd.AddDate(0, 0, 1)) {
+			return true
+		}
+		return timeRangesOverlap(ev.Start, end, rangeStart, rangeEnd)
+	}
+
+	if ev.RawRRule == "" {
+		return timeRangesOverlap(ev.Start, end, rangeStart, rangeEnd)
+	}
+
+	if ev.Start.After(rangeEnd) {
+		return false
+	}
+
+	set, err := buildRuleSet(ev.RawRRule, ev.Start, nil)
+	if err != nil {
+		// Keep it so that expansion reports the invalid RRULE.
+		return true
+	}
+	opts := set.GetRRule().OrigOptions
+	if opts.Until.IsZero() && opts.Count == 0 {
+		return true
+	}
+
+	// With UNTIL the last instance starts no later than UNTIL; with COUNT the
+	// iteration is finite by definition, so asking for the first instance
+	// that still ends inside the window is cheap.
+	dur := end.Sub(ev.Start)
+	if !opts.Until.IsZero() {
+		return !opts.Until.Add(dur).Before(rangeStart)
+	}
+	return !set.After(rangeStart.Add(-dur), true).IsZero()
+}
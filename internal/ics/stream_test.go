@@ -0,0 +1,184 @@
+package ics
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// syntheticFeed builds a feed shaped like a long-lived work calendar: n
+// events spread over the three years before now (most of them history),
+// every tenth one a weekly series.
+func syntheticFeed(n int, now time.Time) []byte {
+	var b bytes.Buffer
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//epdcal//bench//EN\r\n")
+	first := now.AddDate(-3, 0, 0).UTC()
+	step := now.AddDate(0, 0, 30).UTC().Sub(first) / time.Duration(n)
+	for i := range n {
+		start := first.Add(time.Duration(i) * step).Truncate(time.Hour)
+		fmt.Fprintf(&b, "BEGIN:VEVENT\r\nUID:bench-%d@epdcal\r\nDTSTAMP:20250101T000000Z\r\n", i)
+		fmt.Fprintf(&b, "SUMMARY:Meeting %d\r\nDESCRIPTION:Agenda item %d with a reasonably long description line\r\n", i, i)
+		fmt.Fprintf(&b, "DTSTART:%s\r\nDTEND:%s\r\n", start.Format("20060102T150405Z"), start.Add(time.Hour).Format("20060102T150405Z"))
+		if i%10 == 0 {
+			b.WriteString("RRULE:FREQ=WEEKLY;COUNT=20\r\n")
+		}
+		b.WriteString("BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\n")
+		b.WriteString("END:VEVENT\r\n")
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+	return b.Bytes()
+}
+
+func TestParseICSStreamMatchesParseICS(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	for _, name := range []string{"floating_time.ics", "allday_dst.ics"} {
+		body := readFixture(t, name)
+		src := Source{ID: "fixture"}
+		cfg := ParseConfig{FloatingLocation: loc}
+
+		full, err := ParseICS(src, body, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed, err := ParseICSStream(src, bytes.NewReader(body), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(full) != len(streamed) {
+			t.Fatalf("%s: ParseICS %d events, ParseICSStream %d", name, len(full), len(streamed))
+		}
+		for i := range full {
+			a, b := full[i], streamed[i]
+			if a.UID != b.UID || !a.Start.Equal(b.Start) || !a.End.Equal(b.End) ||
+				a.AllDay != b.AllDay || a.RawRRule != b.RawRRule || a.IsOverride != b.IsOverride || a.Line != b.Line {
+				t.Errorf("%s[%d]: ParseICS %+v, ParseICSStream %+v", name, i, a, b)
+			}
+		}
+	}
+}
+
+func TestParseICSStreamDropsEventsOutsideWindow(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	body := syntheticFeed(2000, now)
+	cfg := ParseConfig{FloatingLocation: time.UTC, RangeStart: now.AddDate(0, 0, -7), RangeEnd: now.AddDate(0, 0, 35)}
+
+	streamed, err := ParseICSStream(Source{ID: "bench"}, bytes.NewReader(body), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := ParseICS(Source{ID: "bench"}, body, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every kept event must expand to the same occurrences as the full parse.
+	exp := ExpandConfig{DisplayLocation: time.UTC, RangeStart: cfg.RangeStart, RangeEnd: cfg.RangeEnd}
+	a, _ := ExpandOccurrences(full, exp)
+	b, _ := ExpandOccurrences(streamed, exp)
+	if len(streamed) >= len(full)/2 {
+		t.Errorf("stream kept %d of %d events; expected most history to be dropped", len(streamed), len(full))
+	}
+	if len(a.Occurrences) != len(b.Occurrences) || len(a.Occurrences) == 0 {
+		t.Fatalf("occurrences: full %d, streamed %d", len(a.Occurrences), len(b.Occurrences))
+	}
+	keys := func(occs []model.Occurrence) []string {
+		out := make([]string, len(occs))
+		for i, o := range occs {
+			out[i] = o.UID + "|" + o.Start.UTC().Format(time.RFC3339)
+		}
+		slices.Sort(out)
+		return out
+	}
+	if ka, kb := keys(a.Occurrences), keys(b.Occurrences); !slices.Equal(ka, kb) {
+		t.Errorf("occurrences differ:\nParseICS       %v\nParseICSStream %v", ka, kb)
+	}
+}
+
+// The parse benchmarks compare the two parsers on a ~5 MB feed. Besides
+// B/op (total allocation, ReportAllocs) they report peak-heap-MB: the
+// highest live heap above the baseline during one parse, which is what
+// decides whether a large feed fits on a 512 MB Pi Zero 2 W.
+func benchmarkFeed(b *testing.B) ([]byte, ParseConfig) {
+	b.Helper()
+	now := time.Now().In(time.UTC)
+	body := syntheticFeed(20000, now)
+	return body, ParseConfig{
+		FloatingLocation: time.UTC,
+		RangeStart:       now.AddDate(0, 0, -7),
+		RangeEnd:         now.AddDate(0, 0, 35),
+	}
+}
+
+// peakHeapMB runs fn once while sampling the live heap and returns the
+// peak above the heap before the call, in MB.
+func peakHeapMB(fn func()) float64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	read := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+
+	runtime.GC()
+	base := read()
+	var peak atomic.Uint64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for {
+			if v := read(); v > peak.Load() {
+				peak.Store(v)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(100 * time.Microsecond):
+			}
+		}
+	})
+	fn()
+	close(done)
+	wg.Wait()
+	return float64(peak.Load()-min(base, peak.Load())) / (1 << 20)
+}
+
+func BenchmarkParseICS(b *testing.B) {
+	body, cfg := benchmarkFeed(b)
+	parse := func() {
+		if _, err := ParseICS(Source{ID: "bench"}, body, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+	peak := peakHeapMB(parse)
+
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for b.Loop() {
+		parse()
+	}
+	b.ReportMetric(peak, "peak-heap-MB")
+}
+
+func BenchmarkParseICSStream(b *testing.B) {
+	body, cfg := benchmarkFeed(b)
+	parse := func() {
+		if _, err := ParseICSStream(Source{ID: "bench"}, bytes.NewReader(body), cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+	peak := peakHeapMB(parse)
+
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for b.Loop() {
+		parse()
+	}
+	b.ReportMetric(peak, "peak-heap-MB")
+}
@@ -0,0 +1,287 @@
+package ics
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ical "github.com/arran4/golang-ical"
+
+	appLog "epdcal/internal/log"
+	"epdcal/internal/model"
+)
+
+// ParsedTask is the normalized representation of a VTODO as produced by
+// the ICS parser. Due-date recurrence is expanded by ExpandTasks.
+type ParsedTask struct {
+	Source Source
+
+	UID string
+	Seq int
+
+	Summary     string
+	Description string
+
+	// Start is DTSTART (optional for VTODO). When present it anchors RRULE.
+	Start time.Time
+	// Due is DUE (optional). DueAllDay is true for DATE-only values.
+	Due       time.Time
+	DueAllDay bool
+
+	Priority        int
+	Status          string
+	PercentComplete int
+	Completed       time.Time
+
+	RawRRule   string
+	ExDates    []time.Time
+	Recurrence *time.Time // RECURRENCE-ID (if present)
+	IsOverride bool       // true if this VTODO is an override for a recurring instance
+}
+
+// ParseTasks parses the VTODO components of a single ICS payload.
+//
+// Time handling follows ParseICS: TZID values are resolved via the system
+// timezone database (or the Windows zone name table), floating and DATE
+// values in cfg.FloatingLocation.
+func ParseTasks(src Source, body []byte, cfg ParseConfig) ([]ParsedTask, error) {
+	if len(body) == 0 {
+		return nil, errors.New("empty ICS body")
+	}
+
+	cal, err := ical.ParseCalendar(bytes.NewReader(body))
+	if err != nil {
+		appLog.Error("ics parse failed", err, "id", src.ID, "url", redactURL(src.URL))
+		return nil, err
+	}
+
+	if cfg.FloatingLocation == nil {
+		cfg.FloatingLocation = time.Local
+	}
+
+	tasks := make([]ParsedTask, 0)
+	for _, comp := range cal.Todos() {
+		t, perr := parseVTodo(src, comp, cfg.FloatingLocation)
+		if perr != nil {
+			appLog.Error("ics vtodo parse failed", perr, "id", src.ID, "url", redactURL(src.URL))
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+
+	appLog.Info("ics task parse completed", "id", src.ID, "url", redactURL(src.URL), "task_count", len(tasks))
+	return tasks, nil
+}
+
+func parseVTodo(src Source, vt *ical.VTodo, floating *time.Location) (ParsedTask, error) {
+	var out ParsedTask
+	out.Source = src
+
+	uidProp := vt.GetProperty(ical.ComponentPropertyUniqueId)
+	if uidProp == nil || uidProp.Value == "" {
+		return out, errors.New("missing UID")
+	}
+	out.UID = uidProp.Value
+
+	if p := vt.GetProperty(ical.ComponentPropertySequence); p != nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil {
+			out.Seq = n
+		}
+	}
+	if p := vt.GetProperty(ical.ComponentPropertySummary); p != nil {
+		out.Summary = p.Value
+	}
+	if p := vt.GetProperty(ical.ComponentPropertyDescription); p != nil {
+		out.Description = p.Value
+	}
+
+	if p := vt.GetProperty(ical.ComponentPropertyDtStart); p != nil {
+		if t, _, err := parsePropertyTime(p.Value, p.ICalParameters, floating); err == nil {
+			out.Start = t
+		}
+		noteUnknownTZID(src, firstParam(p.ICalParameters, "TZID"))
+	}
+	if p := vt.GetProperty(ical.ComponentPropertyDue); p != nil {
+		if t, isDate, err := parsePropertyTime(p.Value, p.ICalParameters, floating); err == nil {
+			out.Due = t
+			out.DueAllDay = isDate
+		}
+		noteUnknownTZID(src, firstParam(p.ICalParameters, "TZID"))
+	}
+
+	// PRIORITY: 0-9, anything else is treated as undefined.
+	if p := vt.GetProperty(ical.ComponentPropertyPriority); p != nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil && n >= 0 && n <= 9 {
+			out.Priority = n
+		}
+	}
+	if p := vt.GetProperty(ical.ComponentPropertyStatus); p != nil {
+		out.Status = strings.ToUpper(strings.TrimSpace(p.Value))
+	}
+	if p := vt.GetProperty(ical.ComponentPropertyPercentComplete); p != nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil {
+			out.PercentComplete = min(max(n, 0), 100)
+		}
+	}
+	if p := vt.GetProperty(ical.ComponentPropertyCompleted); p != nil {
+		if t, _, err := parsePropertyTime(p.Value, p.ICalParameters, floating); err == nil {
+			out.Completed = t
+		}
+	}
+
+	if p := vt.GetProperty(ical.ComponentPropertyRrule); p != nil {
+		out.RawRRule = p.Value
+	}
+	out.ExDates = parseExDates(&vt.ComponentBase, floating)
+	if rid, ok := parseRecurrenceID(&vt.ComponentBase, floating); ok {
+		out.Recurrence = &rid
+		out.IsOverride = true
+	}
+
+	return out, nil
+}
+
+// ExpandTasks converts parsed VTODOs into concrete tasks.
+//
+//   - Non-recurring tasks are always returned, whether or not they have a
+//     due date; callers filter by due/overdue/incomplete.
+//   - Recurring tasks are collapsed to a single task: the earliest
+//     incomplete instance whose due date is within [cfg.RangeStart,
+//     cfg.RangeEnd] (or the last instance if every one is complete). The
+//     recurrence is anchored at DTSTART (or DUE if DTSTART is missing) and
+//     each instance keeps the original DTSTART→DUE offset.
+//   - RECURRENCE-ID overrides replace the matching instance, so completing
+//     an instance moves the task on to the next one.
+//
+// Results are sorted by due date (undated tasks last), then priority.
+func ExpandTasks(tasks []ParsedTask, cfg ExpandConfig) ([]model.Task, error) {
+	if cfg.RangeEnd.Before(cfg.RangeStart) {
+		return nil, errors.New("expand: RangeEnd is before RangeStart")
+	}
+	if cfg.DisplayLocation == nil {
+		cfg.DisplayLocation = time.Local
+	}
+	if cfg.MaxOccurrencesPerEvent <= 0 {
+		cfg.MaxOccurrencesPerEvent = defaultMaxOccurrencesPerEvent
+	}
+
+	overridesByUID := make(map[string][]ParsedTask)
+	for _, t := range tasks {
+		if t.IsOverride && t.Recurrence != nil {
+			overridesByUID[t.UID] = append(overridesByUID[t.UID], t)
+		}
+	}
+
+	out := make([]model.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if t.IsOverride && t.Recurrence != nil {
+			continue
+		}
+		if t.RawRRule == "" || (t.Start.IsZero() && t.Due.IsZero()) {
+			out = append(out, makeTask(t, t.Due, cfg.DisplayLocation))
+			continue
+		}
+		out = append(out, expandRecurringTask(t, overridesByUID[t.UID], cfg)...)
+	}
+
+	SortTasks(out)
+	return out, nil
+}
+
+// SortTasks sorts tasks by due date (undated tasks last), then priority.
+func SortTasks(tasks []model.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Due.IsZero() != b.Due.IsZero() {
+			return !a.Due.IsZero()
+		}
+		if !a.Due.Equal(b.Due) {
+			return a.Due.Before(b.Due)
+		}
+		return taskPriorityRank(a.Priority) < taskPriorityRank(b.Priority)
+	})
+}
+
+func expandRecurringTask(t ParsedTask, overrides []ParsedTask, cfg ExpandConfig) []model.Task {
+	anchor := t.Start
+	if anchor.IsZero() {
+		anchor = t.Due
+	}
+	var dueOffset time.Duration
+	if !t.Start.IsZero() && !t.Due.IsZero() {
+		dueOffset = t.Due.Sub(t.Start)
+	}
+
+	set, err := buildRuleSet(t.RawRRule, anchor, t.ExDates)
+	if err != nil {
+		appLog.Error("expand: failed to parse VTODO RRULE", err, "uid", t.UID, "rrule", t.RawRRule)
+		return []model.Task{makeTask(t, t.Due, cfg.DisplayLocation)}
+	}
+
+	// Shift the window so that the range applies to due dates rather than
+	// to recurrence anchors.
+	rangeStart := cfg.RangeStart.Add(-dueOffset).In(anchor.Location())
+	rangeEnd := cfg.RangeEnd.Add(-dueOffset).In(anchor.Location())
+
+	anchors := set.Between(rangeStart, rangeEnd, true)
+	if len(anchors) > cfg.MaxOccurrencesPerEvent {
+		anchors = anchors[:cfg.MaxOccurrencesPerEvent]
+	}
+
+	// A recurring to-do is one task that moves on as instances are
+	// completed; listing every missed instance of the backfill window would
+	// bury the list in overdue rows.
+	var last []model.Task
+	for _, a := range anchors {
+		inst := t
+		due := a.Add(dueOffset)
+		for _, o := range overrides {
+			if o.Recurrence.In(a.Location()).Equal(a) {
+				inst = o
+				due = o.Due
+				break
+			}
+		}
+		task := makeTask(inst, due, cfg.DisplayLocation)
+		if !task.IsComplete() {
+			return []model.Task{task}
+		}
+		last = []model.Task{task}
+	}
+	return last
+}
+
+// makeTask converts a (possibly overridden) ParsedTask with a concrete due
+// time into a model.Task normalized into displayLoc.
+func makeTask(t ParsedTask, due time.Time, displayLoc *time.Location) model.Task {
+	task := model.Task{
+		SourceID:        t.Source.ID,
+		UID:             t.UID,
+		Summary:         t.Summary,
+		Description:     t.Description,
+		DueAllDay:       t.DueAllDay,
+		Priority:        t.Priority,
+		Status:          t.Status,
+		PercentComplete: t.PercentComplete,
+	}
+	if !due.IsZero() {
+		task.Due = due.In(displayLoc)
+		task.InstanceKey = task.Due.Format(time.RFC3339Nano)
+	}
+	if !t.Completed.IsZero() {
+		task.Completed = t.Completed.In(displayLoc)
+	}
+	return task
+}
+
+// taskPriorityRank maps RFC 5545 priorities to a sortable rank where
+// undefined (0) sorts after every explicit priority.
+func taskPriorityRank(p int) int {
+	if p == 0 {
+		return 10
+	}
+	return p
+}
@@ -0,0 +1,60 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const recurringTodo = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//epdcal//fixtures//EN
+BEGIN:VTODO
+UID:daily-chore@epdcal
+DTSTAMP:20250101T000000Z
+SUMMARY:Water the plants
+DTSTART;VALUE=DATE:20250101
+DUE;VALUE=DATE:20250101
+RRULE:FREQ=DAILY
+END:VTODO
+BEGIN:VTODO
+UID:daily-chore@epdcal
+DTSTAMP:20250101T000000Z
+SUMMARY:Water the plants
+RECURRENCE-ID;VALUE=DATE:20250101
+DTSTART;VALUE=DATE:20250101
+DUE;VALUE=DATE:20250101
+STATUS:COMPLETED
+END:VTODO
+END:VCALENDAR
+`
+
+func TestExpandTasksCollapsesRecurring(t *testing.T) {
+	loc := mustLoad(t, "Asia/Seoul")
+	src := Source{ID: "tasks"}
+	parsed, err := ParseTasks(src, []byte(strings.ReplaceAll(recurringTodo, "\n", "\r\n")), ParseConfig{FloatingLocation: loc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 1, 20, 12, 0, 0, 0, loc)
+	tasks, err := ExpandTasks(parsed, ExpandConfig{
+		DisplayLocation: loc,
+		RangeStart:      now.AddDate(0, 0, -30),
+		RangeEnd:        now.AddDate(0, 0, 14),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2025-01-01 is completed, so the earliest incomplete instance is the 2nd.
+	if len(tasks) != 1 {
+		t.Fatalf("got %d tasks, want 1: %v", len(tasks), tasks)
+	}
+	if want := time.Date(2025, 1, 2, 0, 0, 0, 0, loc); !tasks[0].Due.Equal(want) {
+		t.Errorf("due %s, want %s", tasks[0].Due, want)
+	}
+	if !tasks[0].IsOverdue(now) {
+		t.Error("collapsed instance should be overdue")
+	}
+}
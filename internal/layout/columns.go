@@ -0,0 +1,194 @@
+package layout
+
+import (
+	"sort"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// Placement is a timed segment positioned inside its day column.
+type Placement struct {
+	Segment model.Segment
+
+	// Column is the 0-based sub-column and Columns the number of
+	// sub-columns of the overlap cluster the segment belongs to; the
+	// segment is drawn at width 1/Columns, offset Column/Columns.
+	Column  int
+	Columns int
+
+	// Stack is the drawing order within the day (0 is drawn first, i.e.
+	// at the bottom).
+	Stack int
+}
+
+// Overflow summarizes the segments hidden because a day exceeded its
+// capacity. Wording such as "+3 more" is left to the client (Web UI i18n).
+type Overflow struct {
+	Count    int
+	Hidden   []model.Segment
+	Earliest time.Time // start of the earliest hidden segment
+}
+
+// DayLayout is the layout of a single day.
+type DayLayout struct {
+	Day time.Time // 00:00 in the display timezone
+
+	// AllDay holds all-day segments and timed segments moved to the all-day
+	// lane by a rule, in display order.
+	AllDay []model.Segment
+
+	// Timed holds the positioned timed segments, ordered by Stack.
+	Timed []Placement
+
+	// Overflow is nil unless some segments were hidden.
+	Overflow *Overflow
+}
+
+// LayoutDays groups segments (as produced by SplitByDay) by day and
+// computes side-by-side columns for overlapping timed segments.
+//
+// The result is deterministic for a given input set regardless of input
+// order: segments are ordered by start, then longer first, then UID and
+// InstanceKey. Overlapping timed segments form a cluster; each segment takes
+// the first column free at its start and every segment in the cluster
+// shares the cluster's column count.
+//
+// If capacity > 0, at most capacity segments are shown per day (all-day
+// lane first, then timed in order) and the rest are reported in Overflow.
+// Columns are computed for the visible segments only.
+func LayoutDays(segs []model.Segment, capacity int) []DayLayout {
+	byDay := make(map[int64][]model.Segment)
+	days := make([]time.Time, 0)
+	for _, seg := range segs {
+		key := seg.Day.Unix()
+		if _, ok := byDay[key]; !ok {
+			days = append(days, seg.Day)
+		}
+		byDay[key] = append(byDay[key], seg)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	out := make([]DayLayout, 0, len(days))
+	for _, day := range days {
+		out = append(out, layoutDay(day, byDay[day.Unix()], capacity))
+	}
+	return out
+}
+
+func layoutDay(day time.Time, segs []model.Segment, capacity int) DayLayout {
+	var allDay, timed []model.Segment
+	for _, seg := range segs {
+		if seg.Occurrence.AllDay || seg.Occurrence.AllDayLane {
+			allDay = append(allDay, seg)
+		} else {
+			timed = append(timed, seg)
+		}
+	}
+	sortSegments(allDay)
+	sortSegments(timed)
+
+	dl := DayLayout{Day: day}
+
+	if capacity > 0 && len(allDay)+len(timed) > capacity {
+		var hidden []model.Segment
+		if len(allDay) > capacity {
+			hidden = append(hidden, allDay[capacity:]...)
+			allDay = allDay[:capacity]
+		}
+		keep := capacity - len(allDay)
+		hidden = append(hidden, timed[keep:]...)
+		timed = timed[:keep]
+
+		ov := &Overflow{
+			Count:  len(hidden),
+			Hidden: hidden,
+		}
+		for _, seg := range hidden {
+			if ov.Earliest.IsZero() || seg.Start.Before(ov.Earliest) {
+				ov.Earliest = seg.Start
+			}
+		}
+		dl.Overflow = ov
+	}
+
+	dl.AllDay = allDay
+	dl.Timed = placeColumns(timed)
+	return dl
+}
+
+// placeColumns assigns columns to timed segments sorted by sortSegments.
+func placeColumns(timed []model.Segment) []Placement {
+	out := make([]Placement, len(timed))
+
+	clusterStart := 0
+	var clusterEnd time.Time
+	var colEnds []time.Time // end of the last segment in each column
+
+	closeCluster := func(upto int) {
+		for i := clusterStart; i < upto; i++ {
+			out[i].Columns = len(colEnds)
+		}
+	}
+
+	for i, seg := range timed {
+		start, end := seg.Start, effectiveEnd(seg)
+		if i > 0 && !start.Before(clusterEnd) {
+			// No overlap with anything in the current cluster.
+			closeCluster(i)
+			clusterStart = i
+			colEnds = colEnds[:0]
+		}
+
+		col := -1
+		for c, ce := range colEnds {
+			if !start.Before(ce) {
+				col = c
+				break
+			}
+		}
+		if col < 0 {
+			col = len(colEnds)
+			colEnds = append(colEnds, end)
+		} else {
+			colEnds[col] = end
+		}
+		if i == clusterStart || end.After(clusterEnd) {
+			clusterEnd = end
+		}
+
+		out[i] = Placement{Segment: seg, Column: col, Stack: i}
+	}
+	closeCluster(len(timed))
+	return out
+}
+
+// effectiveEnd gives zero-length segments a minimal height so that they
+// still take part in overlap detection.
+func effectiveEnd(seg model.Segment) time.Time {
+	if !seg.End.After(seg.Start) {
+		return seg.Start.Add(time.Minute)
+	}
+	return seg.End
+}
+
+// sortSegments orders segments by start, longer first, then by identity so
+// that equal-looking segments keep a stable order between runs.
+func sortSegments(segs []model.Segment) {
+	sort.SliceStable(segs, func(i, j int) bool {
+		a, b := segs[i], segs[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if !a.End.Equal(b.End) {
+			return a.End.After(b.End)
+		}
+		if a.Occurrence.UID != b.Occurrence.UID {
+			return a.Occurrence.UID < b.Occurrence.UID
+		}
+		if a.Occurrence.InstanceKey != b.Occurrence.InstanceKey {
+			return a.Occurrence.InstanceKey < b.Occurrence.InstanceKey
+		}
+		return a.Occurrence.SourceID < b.Occurrence.SourceID
+	})
+}
@@ -0,0 +1,252 @@
+package layout
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+var day0 = time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+// timed returns a segment on day0 from hh:mm to hh:mm (minutes since 00:00).
+func timed(uid string, startMin, endMin int) model.Segment {
+	return model.Segment{
+		Occurrence: model.Occurrence{UID: uid, Summary: uid},
+		Day:        day0,
+		Start:      day0.Add(time.Duration(startMin) * time.Minute),
+		End:        day0.Add(time.Duration(endMin) * time.Minute),
+	}
+}
+
+func allDaySeg(uid string) model.Segment {
+	seg := timed(uid, 0, 24*60)
+	seg.Occurrence.AllDay = true
+	return seg
+}
+
+// placed renders placements as "uid:column/columns" in Stack order.
+func placed(ps []Placement) []string {
+	out := make([]string, len(ps))
+	for i, p := range ps {
+		if p.Stack != i {
+			out[i] = fmt.Sprintf("%s:stack%d", p.Segment.Occurrence.UID, p.Stack)
+			continue
+		}
+		out[i] = fmt.Sprintf("%s:%d/%d", p.Segment.Occurrence.UID, p.Column, p.Columns)
+	}
+	return out
+}
+
+func TestLayoutColumns(t *testing.T) {
+	h := 60
+	tests := []struct {
+		name string
+		segs []model.Segment
+		want []string
+	}{
+		{
+			name: "no overlap",
+			segs: []model.Segment{timed("a", 9*h, 10*h), timed("b", 10*h, 11*h)},
+			want: []string{"a:0/1", "b:0/1"},
+		},
+		{
+			name: "pair overlap",
+			segs: []model.Segment{timed("b", 9*h+30, 11*h), timed("a", 9*h, 10*h)},
+			want: []string{"a:0/2", "b:1/2"},
+		},
+		{
+			// c starts when a ends and reuses column 0; the cluster keeps
+			// two columns.
+			name: "freed column is reused",
+			segs: []model.Segment{timed("a", 9*h, 11*h), timed("b", 10*h, 12*h), timed("c", 11*h, 12*h)},
+			want: []string{"a:0/2", "b:1/2", "c:0/2"},
+		},
+		{
+			name: "separate clusters have their own column count",
+			segs: []model.Segment{
+				timed("a", 9*h, 10*h), timed("b", 9*h, 10*h), timed("c", 9*h, 10*h),
+				timed("d", 13*h, 14*h),
+			},
+			want: []string{"a:0/3", "b:1/3", "c:2/3", "d:0/1"},
+		},
+		{
+			name: "same start puts the longer segment first",
+			segs: []model.Segment{timed("short", 9*h, 10*h), timed("long", 9*h, 12*h)},
+			want: []string{"long:0/2", "short:1/2"},
+		},
+		{
+			name: "long segment chains a cluster",
+			segs: []model.Segment{timed("day", 8*h, 17*h), timed("a", 9*h, 10*h), timed("b", 15*h, 16*h)},
+			want: []string{"day:0/2", "a:1/2", "b:1/2"},
+		},
+		{
+			name: "zero-length still overlaps",
+			segs: []model.Segment{timed("a", 9*h, 10*h), timed("reminder", 9*h, 9*h)},
+			want: []string{"a:0/2", "reminder:1/2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := LayoutDays(tt.segs, 0)
+			if len(days) != 1 {
+				t.Fatalf("got %d days, want 1", len(days))
+			}
+			if got := placed(days[0].Timed); !slices.Equal(got, tt.want) {
+				t.Errorf("placements %v, want %v", got, tt.want)
+			}
+			if days[0].Overflow != nil {
+				t.Errorf("unexpected overflow %+v", days[0].Overflow)
+			}
+		})
+	}
+}
+
+// TestLayoutDeterministic checks that every input order yields the same
+// columns, stack order and overflow.
+func TestLayoutDeterministic(t *testing.T) {
+	h := 60
+	segs := []model.Segment{
+		timed("a", 9*h, 10*h),
+		timed("b", 9*h, 10*h),
+		timed("c", 9*h+30, 11*h),
+		timed("d", 10*h, 12*h),
+		allDaySeg("holiday"),
+	}
+	// Same UID and times, told apart by InstanceKey.
+	e1, e2 := timed("e", 14*h, 15*h), timed("e", 14*h, 15*h)
+	e1.Occurrence.InstanceKey, e2.Occurrence.InstanceKey = "1", "2"
+	segs = append(segs, e2, e1)
+
+	describe := func(dl DayLayout) string {
+		s := fmt.Sprint(placed(dl.Timed))
+		for _, p := range dl.Timed {
+			s += " " + p.Segment.Occurrence.InstanceKey
+		}
+		for _, seg := range dl.AllDay {
+			s += " allday:" + seg.Occurrence.UID
+		}
+		if dl.Overflow != nil {
+			for _, seg := range dl.Overflow.Hidden {
+				s += " hidden:" + seg.Occurrence.UID + seg.Occurrence.InstanceKey
+			}
+		}
+		return s
+	}
+
+	for _, capacity := range []int{0, 4} {
+		want := describe(LayoutDays(segs, capacity)[0])
+		perm := make([]model.Segment, len(segs))
+		for shift := 1; shift < len(segs); shift++ {
+			for i := range segs {
+				perm[i] = segs[(i*3+shift)%len(segs)]
+			}
+			if got := describe(LayoutDays(perm, capacity)[0]); got != want {
+				t.Errorf("capacity %d, permutation %d:\n got %s\nwant %s", capacity, shift, got, want)
+			}
+		}
+	}
+}
+
+func TestLayoutOverflow(t *testing.T) {
+	h := 60
+	tests := []struct {
+		name       string
+		segs       []model.Segment
+		capacity   int
+		allDay     []string
+		timed      []string
+		hidden     []string
+		earliestAt int // minutes since 00:00
+	}{
+		{
+			name:     "within capacity",
+			segs:     []model.Segment{allDaySeg("x"), timed("a", 9*h, 10*h)},
+			capacity: 2,
+			allDay:   []string{"x"},
+			timed:    []string{"a:0/1"},
+		},
+		{
+			// Columns are computed for the visible segments only: b is
+			// hidden, so a no longer shares its row.
+			name:       "timed segments are hidden after the all-day lane",
+			segs:       []model.Segment{allDaySeg("x"), timed("a", 9*h, 10*h), timed("b", 9*h+30, 11*h), timed("c", 13*h, 14*h)},
+			capacity:   2,
+			allDay:     []string{"x"},
+			timed:      []string{"a:0/1"},
+			hidden:     []string{"b", "c"},
+			earliestAt: 9*h + 30,
+		},
+		{
+			name:       "all-day lane alone exceeds capacity",
+			segs:       []model.Segment{allDaySeg("x"), allDaySeg("y"), allDaySeg("z"), timed("a", 9*h, 10*h)},
+			capacity:   2,
+			allDay:     []string{"x", "y"},
+			hidden:     []string{"z", "a"},
+			earliestAt: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dl := LayoutDays(tt.segs, tt.capacity)[0]
+
+			var allDay []string
+			for _, seg := range dl.AllDay {
+				allDay = append(allDay, seg.Occurrence.UID)
+			}
+			if !slices.Equal(allDay, tt.allDay) {
+				t.Errorf("all-day %v, want %v", allDay, tt.allDay)
+			}
+			if got := placed(dl.Timed); !slices.Equal(got, tt.timed) {
+				t.Errorf("timed %v, want %v", got, tt.timed)
+			}
+
+			if len(tt.hidden) == 0 {
+				if dl.Overflow != nil {
+					t.Errorf("unexpected overflow %+v", dl.Overflow)
+				}
+				return
+			}
+			if dl.Overflow == nil {
+				t.Fatalf("no overflow, want %v hidden", tt.hidden)
+			}
+			var hidden []string
+			for _, seg := range dl.Overflow.Hidden {
+				hidden = append(hidden, seg.Occurrence.UID)
+			}
+			if dl.Overflow.Count != len(tt.hidden) || !slices.Equal(hidden, tt.hidden) {
+				t.Errorf("overflow %d %v, want %d %v", dl.Overflow.Count, hidden, len(tt.hidden), tt.hidden)
+			}
+			if want := day0.Add(time.Duration(tt.earliestAt) * time.Minute); !dl.Overflow.Earliest.Equal(want) {
+				t.Errorf("earliest hidden %v, want %v", dl.Overflow.Earliest, want)
+			}
+		})
+	}
+}
+
+func TestLayoutDaysGroupsByDay(t *testing.T) {
+	h := 60
+	lane := timed("moved", 9*h, 10*h)
+	lane.Occurrence.AllDayLane = true
+
+	next := timed("next", 9*h, 10*h)
+	next.Day = day0.AddDate(0, 0, 1)
+	next.Start = next.Start.AddDate(0, 0, 1)
+	next.End = next.End.AddDate(0, 0, 1)
+
+	days := LayoutDays([]model.Segment{next, timed("a", 9*h, 10*h), lane}, 0)
+	if len(days) != 2 || !days[0].Day.Equal(day0) || !days[1].Day.Equal(next.Day) {
+		t.Fatalf("days not grouped in order: %+v", days)
+	}
+	if len(days[0].AllDay) != 1 || days[0].AllDay[0].Occurrence.UID != "moved" {
+		t.Errorf("AllDayLane segment not in the all-day lane: %+v", days[0].AllDay)
+	}
+	if got := placed(days[0].Timed); !slices.Equal(got, []string{"a:0/1"}) {
+		t.Errorf("day 0 timed %v, want [a:0/1]", got)
+	}
+	if got := placed(days[1].Timed); !slices.Equal(got, []string{"next:0/1"}) {
+		t.Errorf("day 1 timed %v, want [next:0/1]", got)
+	}
+}
@@ -0,0 +1,104 @@
+package layout
+
+import (
+	"sort"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// SplitByDay splits occurrences into per-day segments in loc.
+//
+//   - A timed occurrence produces one segment for every day it touches; an
+//     end exactly at midnight does not produce an empty segment on the next
+//     day. Zero-length occurrences produce a single segment.
+//   - All-day occurrences produce one full-day segment per covered date.
+//   - Only days within [rangeStart, rangeEnd) are emitted, but DayIndex,
+//     DayCount and the continues-from/to flags always refer to the full span.
+//     A zero rangeStart/rangeEnd leaves that side unbounded.
+//
+// Day boundaries use calendar-day arithmetic (AddDate), so 23h/25h DST days
+// are handled correctly. Segments are ordered by day, then all-day first,
+// then by start time.
+func SplitByDay(occs []model.Occurrence, loc *time.Location, rangeStart, rangeEnd time.Time) []model.Segment {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	out := make([]model.Segment, 0, len(occs))
+	for _, occ := range occs {
+		start := occ.Start.In(loc)
+		end := occ.End.In(loc)
+		if end.Before(start) {
+			end = start
+		}
+
+		first := startOfDay(start)
+		last := startOfDay(end)
+		if end.Equal(last) && end.After(start) {
+			// Ends exactly at midnight: the last covered day is the previous one.
+			last = last.AddDate(0, 0, -1)
+		}
+
+		count := 0
+		for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+			count++
+		}
+
+		idx := 0
+		for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+			next := d.AddDate(0, 0, 1)
+			inRange := (rangeStart.IsZero() || next.After(rangeStart)) &&
+				(rangeEnd.IsZero() || d.Before(rangeEnd))
+			if inRange {
+				seg := model.Segment{
+					Occurrence:            occ,
+					Day:                   d,
+					Start:                 maxTime(start, d),
+					End:                   minTime(end, next),
+					ContinuesFromPrevious: idx > 0,
+					ContinuesToNext:       idx < count-1,
+					DayIndex:              idx,
+					DayCount:              count,
+				}
+				if occ.AllDay {
+					seg.Start = d
+					seg.End = next
+				}
+				out = append(out, seg)
+			}
+			idx++
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if !a.Day.Equal(b.Day) {
+			return a.Day.Before(b.Day)
+		}
+		if a.Occurrence.AllDay != b.Occurrence.AllDay {
+			return a.Occurrence.AllDay
+		}
+		return a.Start.Before(b.Start)
+	})
+	return out
+}
+
+// startOfDay returns 00:00 of t's date in t's location.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,244 @@
+package layout
+
+import (
+	"testing"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+// wantSeg is the expected shape of one segment, in the test's location.
+type wantSeg struct {
+	day        string // YYYY-MM-DD
+	start, end string // HH:MM; "24:00" is the next midnight
+	from, to   bool   // ContinuesFromPrevious / ContinuesToNext
+	index      int
+}
+
+func checkSegments(t *testing.T, got []model.Segment, count int, want []wantSeg) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		seg := got[i]
+		if d := seg.Day.Format("2006-01-02"); d != w.day || seg.Day.Hour() != 0 {
+			t.Errorf("segment %d: day %v, want %s 00:00", i, seg.Day, w.day)
+		}
+		if s := clock(seg.Day, seg.Start); s != w.start {
+			t.Errorf("segment %d: start %s, want %s", i, s, w.start)
+		}
+		if e := clock(seg.Day, seg.End); e != w.end {
+			t.Errorf("segment %d: end %s, want %s", i, e, w.end)
+		}
+		if seg.ContinuesFromPrevious != w.from || seg.ContinuesToNext != w.to {
+			t.Errorf("segment %d: continues from/to %v/%v, want %v/%v", i, seg.ContinuesFromPrevious, seg.ContinuesToNext, w.from, w.to)
+		}
+		if seg.DayIndex != w.index || seg.DayCount != count {
+			t.Errorf("segment %d: day %d of %d, want %d of %d", i, seg.DayIndex, seg.DayCount, w.index, count)
+		}
+	}
+}
+
+// clock formats t as HH:MM, or "24:00" if t is the midnight after day.
+func clock(day, t time.Time) string {
+	if t.Equal(day.AddDate(0, 0, 1)) {
+		return "24:00"
+	}
+	return t.Format("15:04")
+}
+
+func TestSplitByDay(t *testing.T) {
+	seoul := mustLoad(t, "Asia/Seoul")
+	at := func(day, hm string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", day+" "+hm, seoul)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name  string
+		occ   model.Occurrence
+		count int
+		want  []wantSeg
+	}{
+		{
+			name:  "within one day",
+			occ:   model.Occurrence{Start: at("2025-03-10", "09:00"), End: at("2025-03-10", "10:00")},
+			count: 1,
+			want:  []wantSeg{{"2025-03-10", "09:00", "10:00", false, false, 0}},
+		},
+		{
+			name:  "crosses midnight",
+			occ:   model.Occurrence{Start: at("2025-03-10", "22:00"), End: at("2025-03-11", "02:00")},
+			count: 2,
+			want: []wantSeg{
+				{"2025-03-10", "22:00", "24:00", false, true, 0},
+				{"2025-03-11", "00:00", "02:00", true, false, 1},
+			},
+		},
+		{
+			name:  "ends exactly at midnight",
+			occ:   model.Occurrence{Start: at("2025-03-10", "22:00"), End: at("2025-03-11", "00:00")},
+			count: 1,
+			want:  []wantSeg{{"2025-03-10", "22:00", "24:00", false, false, 0}},
+		},
+		{
+			name:  "zero length at midnight",
+			occ:   model.Occurrence{Start: at("2025-03-11", "00:00"), End: at("2025-03-11", "00:00")},
+			count: 1,
+			want:  []wantSeg{{"2025-03-11", "00:00", "00:00", false, false, 0}},
+		},
+		{
+			name:  "end before start is clamped",
+			occ:   model.Occurrence{Start: at("2025-03-10", "09:00"), End: at("2025-03-10", "08:00")},
+			count: 1,
+			want:  []wantSeg{{"2025-03-10", "09:00", "09:00", false, false, 0}},
+		},
+		{
+			name:  "multi-day all-day",
+			occ:   model.Occurrence{AllDay: true, Start: at("2025-03-10", "00:00"), End: at("2025-03-13", "00:00")},
+			count: 3,
+			want: []wantSeg{
+				{"2025-03-10", "00:00", "24:00", false, true, 0},
+				{"2025-03-11", "00:00", "24:00", true, true, 1},
+				{"2025-03-12", "00:00", "24:00", true, false, 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitByDay([]model.Occurrence{tt.occ}, seoul, time.Time{}, time.Time{})
+			checkSegments(t, got, tt.count, tt.want)
+		})
+	}
+}
+
+func TestSplitByDayConvertsToDisplayZone(t *testing.T) {
+	seoul := mustLoad(t, "Asia/Seoul")
+	// 14:00–16:00 UTC is 23:00–01:00 in Seoul.
+	occ := model.Occurrence{
+		Start: time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC),
+		End:   time.Date(2025, 3, 10, 16, 0, 0, 0, time.UTC),
+	}
+	got := SplitByDay([]model.Occurrence{occ}, seoul, time.Time{}, time.Time{})
+	checkSegments(t, got, 2, []wantSeg{
+		{"2025-03-10", "23:00", "24:00", false, true, 0},
+		{"2025-03-11", "00:00", "01:00", true, false, 1},
+	})
+	if got[0].Day.Location() != seoul {
+		t.Errorf("segment day in %v, want Asia/Seoul", got[0].Day.Location())
+	}
+}
+
+// TestSplitByDayDST checks that day boundaries follow the calendar on 23h and
+// 25h days instead of adding 24h.
+func TestSplitByDayDST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	at := func(day, hm string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", day+" "+hm, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name    string
+		occ     model.Occurrence
+		want    []wantSeg
+		dayLens []time.Duration
+	}{
+		{
+			// 2025-03-09 has 23 hours.
+			name: "spring forward",
+			occ:  model.Occurrence{Start: at("2025-03-08", "22:00"), End: at("2025-03-10", "02:00")},
+			want: []wantSeg{
+				{"2025-03-08", "22:00", "24:00", false, true, 0},
+				{"2025-03-09", "00:00", "24:00", true, true, 1},
+				{"2025-03-10", "00:00", "02:00", true, false, 2},
+			},
+			dayLens: []time.Duration{2 * time.Hour, 23 * time.Hour, 2 * time.Hour},
+		},
+		{
+			// 2025-11-02 has 25 hours.
+			name: "fall back",
+			occ:  model.Occurrence{Start: at("2025-11-01", "22:00"), End: at("2025-11-03", "02:00")},
+			want: []wantSeg{
+				{"2025-11-01", "22:00", "24:00", false, true, 0},
+				{"2025-11-02", "00:00", "24:00", true, true, 1},
+				{"2025-11-03", "00:00", "02:00", true, false, 2},
+			},
+			dayLens: []time.Duration{2 * time.Hour, 25 * time.Hour, 2 * time.Hour},
+		},
+		{
+			name: "all-day across spring forward",
+			occ:  model.Occurrence{AllDay: true, Start: at("2025-03-09", "00:00"), End: at("2025-03-11", "00:00")},
+			want: []wantSeg{
+				{"2025-03-09", "00:00", "24:00", false, true, 0},
+				{"2025-03-10", "00:00", "24:00", true, false, 1},
+			},
+			dayLens: []time.Duration{23 * time.Hour, 24 * time.Hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitByDay([]model.Occurrence{tt.occ}, ny, time.Time{}, time.Time{})
+			checkSegments(t, got, len(tt.want), tt.want)
+			for i, seg := range got {
+				if d := seg.End.Sub(seg.Start); d != tt.dayLens[i] {
+					t.Errorf("segment %d lasts %v, want %v", i, d, tt.dayLens[i])
+				}
+			}
+		})
+	}
+}
+
+// TestSplitByDayRange checks that clipping to the range keeps the indexes and
+// continuation flags of the full span.
+func TestSplitByDayRange(t *testing.T) {
+	loc := time.UTC
+	occ := model.Occurrence{
+		AllDay: true,
+		Start:  time.Date(2025, 3, 8, 0, 0, 0, 0, loc),
+		End:    time.Date(2025, 3, 13, 0, 0, 0, 0, loc),
+	}
+	got := SplitByDay([]model.Occurrence{occ}, loc,
+		time.Date(2025, 3, 10, 0, 0, 0, 0, loc), time.Date(2025, 3, 12, 0, 0, 0, 0, loc))
+	checkSegments(t, got, 5, []wantSeg{
+		{"2025-03-10", "00:00", "24:00", true, true, 2},
+		{"2025-03-11", "00:00", "24:00", true, true, 3},
+	})
+}
+
+func TestSplitByDayOrder(t *testing.T) {
+	loc := time.UTC
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, loc)
+	occs := []model.Occurrence{
+		{UID: "late", Start: day.Add(15 * time.Hour), End: day.Add(16 * time.Hour)},
+		{UID: "next-day", Start: day.Add(33 * time.Hour), End: day.Add(34 * time.Hour)},
+		{UID: "early", Start: day.Add(9 * time.Hour), End: day.Add(10 * time.Hour)},
+		{UID: "holiday", AllDay: true, Start: day, End: day.AddDate(0, 0, 1)},
+	}
+	got := SplitByDay(occs, loc, time.Time{}, time.Time{})
+	want := []string{"holiday", "early", "late", "next-day"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Occurrence.UID != w {
+			t.Errorf("segment %d = %s, want %s", i, got[i].Occurrence.UID, w)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+// epdcal pipeline metrics. Sources are labeled with their configured ID, so
+// cardinality is bounded by the config file.
+var (
+	// Fetch (internal/ics/fetch.go). status is the HTTP status code, or
+	// "error" for network failures.
+	FetchDuration = NewHistogramVec("epdcal_source_fetch_duration_seconds",
+		"Time spent fetching an ICS source.", nil, "source")
+	FetchTotal = NewCounterVec("epdcal_source_fetch_total",
+		"ICS fetches by outcome.", "source", "status")
+	FetchCacheHits = NewCounterVec("epdcal_source_fetch_cache_hits_total",
+		"ICS fetches served from the on-disk cache (304 or fallback after a failure).", "source")
+
+	// Parse (internal/ics/stream.go).
+	ParseEvents = NewGaugeVec("epdcal_parse_events",
+		"VEVENTs kept by the last parse of a source.", "source")
+	ParseErrors = NewCounterVec("epdcal_parse_errors_total",
+		"VEVENTs skipped or whole feeds rejected while parsing.", "source")
+
+	// Expansion (cmd/epdcal refresh cycle).
+	ExpandOccurrences = NewGaugeVec("epdcal_expand_occurrences",
+		"Occurrences produced by the last expansion of a source.", "source")
+	ExpandTruncatedEvents = NewGaugeVec("epdcal_expand_truncated_events",
+		"Events whose expansion hit the per-event occurrence cap in the last cycle.", "source")
+
+	// Refresh cycle. outcome is success, partial or failed.
+	RefreshCycles = NewCounterVec("epdcal_refresh_cycles_total",
+		"Refresh cycles by outcome.", "outcome")
+	RefreshDuration = NewHistogramVec("epdcal_refresh_duration_seconds",
+		"Duration of a refresh cycle (fetch, parse, expand, publish).", nil)
+	RefreshLastSuccess = NewGaugeVec("epdcal_refresh_last_success_timestamp_seconds",
+		"Unix time of the last refresh cycle without source errors.")
+
+	// Render / display pipeline.
+	CaptureDuration = NewHistogramVec("epdcal_capture_duration_seconds",
+		"Duration of the headless Chromium capture.", nil)
+	CaptureFailures = NewCounterVec("epdcal_capture_failures_total",
+		"Failed headless Chromium captures.")
+	PackDuration = NewHistogramVec("epdcal_pack_duration_seconds",
+		"Time spent packing the captured PNG into EPD planes.", nil)
+	DisplayDuration = NewHistogramVec("epdcal_display_duration_seconds",
+		"Duration of an EPD display update.", nil)
+	DisplayFailures = NewCounterVec("epdcal_display_failures_total",
+		"Failed EPD display updates.")
+
+	// Battery (updated whenever the battery is read).
+	BatteryPercent = NewGaugeVec("epdcal_battery_percent",
+		"Battery charge in percent.")
+	BatteryVoltage = NewGaugeVec("epdcal_battery_voltage_volts",
+		"Battery voltage in volts.")
+)
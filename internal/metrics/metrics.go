@@ -0,0 +1,356 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// This package implements the small subset of the Prometheus text
+// exposition format (version 0.0.4) that epdcal needs: counters, gauges and
+// histograms with string labels. It has no dependencies so that every
+// pipeline stage can record into it without pulling in client_golang.
+
+// DefBuckets are the default histogram buckets in seconds, covering a
+// fast cache hit up to a slow Chromium capture or EPD refresh.
+var DefBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// collector is a metric family that can write itself in text format.
+type collector interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families in registration order.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Default is the registry used by the package-level constructors.
+var Default = NewRegistry()
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[c.name()] {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	r.names[c.name()] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText writes every registered family in Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	cs := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range cs {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry in Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// family is the label bookkeeping shared by all metric kinds.
+type family[T any] struct {
+	fname  string
+	help   string
+	kind   string
+	labels []string
+	newFn  func() *T
+
+	mu     sync.Mutex
+	series map[string]*T
+	values map[string][]string
+}
+
+func newFamily[T any](name, help, kind string, labels []string, newFn func() *T) *family[T] {
+	return &family[T]{
+		fname:  name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		newFn:  newFn,
+		series: make(map[string]*T),
+		values: make(map[string][]string),
+	}
+}
+
+func (f *family[T]) name() string { return f.fname }
+
+// with returns the series for the given label values, creating it on first
+// use. It panics on a label count mismatch, which is a programming error.
+func (f *family[T]) with(values []string) *T {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", f.fname, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	s := f.newFn()
+	f.series[key] = s
+	f.values[key] = append([]string(nil), values...)
+	return s
+}
+
+// each calls fn for every series ordered by label values.
+func (f *family[T]) each(fn func(labels []string, s *T)) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type entry struct {
+		values []string
+		s      *T
+	}
+	entries := make([]entry, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, entry{f.values[k], f.series[k]})
+	}
+	f.mu.Unlock()
+
+	for _, e := range entries {
+		fn(e.values, e.s)
+	}
+}
+
+func (f *family[T]) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.fname, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.fname, f.kind)
+}
+
+// labelString renders {a="x",b="y"} plus an optional extra pair.
+func labelString(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraName)
+		b.WriteString(`="`)
+		b.WriteString(extraValue)
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escapeLabel(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+func escapeHelp(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// atomicFloat is a float64 guarded by a mutex; contention is negligible at
+// epdcal's update rates.
+type atomicFloat struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (a *atomicFloat) add(d float64) {
+	a.mu.Lock()
+	a.v += d
+	a.mu.Unlock()
+}
+
+func (a *atomicFloat) set(v float64) {
+	a.mu.Lock()
+	a.v = v
+	a.mu.Unlock()
+}
+
+func (a *atomicFloat) get() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.v
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct{ v atomicFloat }
+
+// Inc adds 1.
+func (c *Counter) Inc() { c.v.add(1) }
+
+// Add adds d, which must not be negative.
+func (c *Counter) Add(d float64) {
+	if d < 0 {
+		return
+	}
+	c.v.add(d)
+}
+
+// CounterVec is a counter family partitioned by labels.
+type CounterVec struct{ f *family[Counter] }
+
+// NewCounterVec registers a counter family in Default.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newFamily(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	Default.register(v)
+	return v
+}
+
+// With returns the counter for the given label values.
+func (v *CounterVec) With(values ...string) *Counter { return v.f.with(values) }
+
+func (v *CounterVec) name() string { return v.f.name() }
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.f.writeHeader(w)
+	v.f.each(func(values []string, c *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", v.f.fname, labelString(v.f.labels, values, "", ""), formatFloat(c.v.get()))
+	})
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct{ v atomicFloat }
+
+// Set sets the gauge to x.
+func (g *Gauge) Set(x float64) { g.v.set(x) }
+
+// GaugeVec is a gauge family partitioned by labels.
+type GaugeVec struct{ f *family[Gauge] }
+
+// NewGaugeVec registers a gauge family in Default.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{newFamily(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	Default.register(v)
+	return v
+}
+
+// With returns the gauge for the given label values.
+func (v *GaugeVec) With(values ...string) *Gauge { return v.f.with(values) }
+
+func (v *GaugeVec) name() string { return v.f.name() }
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.f.writeHeader(w)
+	v.f.each(func(values []string, g *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", v.f.fname, labelString(v.f.labels, values, "", ""), formatFloat(g.v.get()))
+	})
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64 // upper bounds, ascending
+	counts  []uint64  // per bucket (non-cumulative)
+	count   uint64
+	sum     float64
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(x float64) {
+	i := sort.SearchFloat64s(h.buckets, x)
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += x
+	h.mu.Unlock()
+}
+
+// HistogramVec is a histogram family partitioned by labels.
+type HistogramVec struct {
+	f       *family[Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram family in Default. nil buckets
+// means DefBuckets.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	v := &HistogramVec{buckets: b}
+	v.f = newFamily(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{buckets: b, counts: make([]uint64, len(b))}
+	})
+	Default.register(v)
+	return v
+}
+
+// With returns the histogram for the given label values.
+func (v *HistogramVec) With(values ...string) *Histogram { return v.f.with(values) }
+
+func (v *HistogramVec) name() string { return v.f.name() }
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.f.writeHeader(w)
+	v.f.each(func(values []string, h *Histogram) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		count, sum := h.count, h.sum
+		h.mu.Unlock()
+
+		var cum uint64
+		for i, ub := range v.buckets {
+			cum += counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.f.fname, labelString(v.f.labels, values, "le", formatFloat(ub)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.f.fname, labelString(v.f.labels, values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.f.fname, labelString(v.f.labels, values, "", ""), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.f.fname, labelString(v.f.labels, values, "", ""), count)
+	})
+}
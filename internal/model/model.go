@@ -27,6 +27,10 @@ type Occurrence struct {
 	SourceID string // calendar source ID
 	UID      string // iCalendar UID
 
+	// SourceIDs lists every source that reported this occurrence after
+	// cross-source dedup (internal/dedup), SourceID first. nil before dedup.
+	SourceIDs []string
+
 	// InstanceKey uniquely identifies a single occurrence of a recurring
 	// event, typically derived from the local start time.
 	InstanceKey string
@@ -35,9 +39,135 @@ type Occurrence struct {
 	Description string
 	Location    string
 
+	Categories []string
+	URL        string
+	Color      string // RFC 7986 COLOR (CSS3 color name), if any
+	Priority   int    // 0 = undefined, 1 = highest, 9 = lowest
+
+	// Transparent is true for TRANSP:TRANSPARENT events, which do not block
+	// time and never take part in conflict detection.
+	Transparent bool
+
+	// AlarmOffset is the earliest VALARM trigger relative to Start
+	// (negative = before start). nil if the event has no alarm.
+	AlarmOffset *time.Duration
+
 	AllDay bool
 
 	// Start / End are in the configured display timezone.
 	Start time.Time
 	End   time.Time
+
+	// RecurrenceID is the original start of this instance for occurrences of
+	// recurring events (the RRULE start even if an override moved it). Zero
+	// for single events.
+	RecurrenceID time.Time
+
+	// Display classification set by the rule engine (internal/rules).
+	// AllDayLane shows a timed occurrence in the all-day lane; Ink is the
+	// panel plane it is drawn with.
+	AllDayLane bool
+	Ink        Ink
+
+	// Conflict is set by conflict detection (internal/conflict) when the
+	// occurrence overlaps another busy occurrence.
+	Conflict bool
+}
+
+// Ink is the e-paper plane an occurrence is drawn with.
+type Ink string
+
+const (
+	InkDefault Ink = "" // renderer's choice (black)
+	InkBlack   Ink = "black"
+	InkRed     Ink = "red"
+)
+
+// HasAlarm reports whether the occurrence has at least one VALARM.
+func (o Occurrence) HasAlarm() bool {
+	return o.AlarmOffset != nil
+}
+
+// IsHighPriority reports whether PRIORITY is in the RFC 5545 "high" band (1–4).
+func (o Occurrence) IsHighPriority() bool {
+	return o.Priority >= 1 && o.Priority <= 4
+}
+
+// Task represents a single concrete to-do item (VTODO), after recurrence
+// expansion of its due date and timezone normalization.
+type Task struct {
+	SourceID string // calendar source ID
+	UID      string // iCalendar UID
+
+	// InstanceKey uniquely identifies a single instance of a recurring
+	// task, typically derived from the local due time.
+	InstanceKey string
+
+	Summary     string
+	Description string
+
+	// Due is in the configured display timezone; zero if the task has no
+	// due date. DueAllDay is true for DATE-only due values.
+	Due       time.Time
+	DueAllDay bool
+
+	// Priority follows RFC 5545: 0 = undefined, 1 = highest, 9 = lowest.
+	Priority int
+
+	// Status is the raw STATUS value (NEEDS-ACTION, IN-PROCESS, COMPLETED,
+	// CANCELLED) or empty if not set.
+	Status string
+
+	// PercentComplete is 0–100.
+	PercentComplete int
+
+	// Completed is the COMPLETED timestamp, zero if not completed.
+	Completed time.Time
+}
+
+// IsComplete reports whether the task is done (completed or cancelled).
+func (t Task) IsComplete() bool {
+	switch t.Status {
+	case "COMPLETED", "CANCELLED":
+		return true
+	}
+	return !t.Completed.IsZero() || t.PercentComplete >= 100
+}
+
+// IsOverdue reports whether the task is incomplete and its due time has
+// passed at now. All-day tasks become overdue after their due day ends.
+func (t Task) IsOverdue(now time.Time) bool {
+	if t.Due.IsZero() || t.IsComplete() {
+		return false
+	}
+	due := t.Due
+	if t.DueAllDay {
+		due = due.AddDate(0, 0, 1)
+	}
+	return now.After(due)
+}
+
+// Segment is the part of an Occurrence that falls on a single day in the
+// display timezone. Multi-day and midnight-spanning occurrences produce one
+// Segment per day they touch.
+type Segment struct {
+	// Occurrence is the original occurrence with its full Start/End span.
+	Occurrence Occurrence
+
+	// Day is 00:00 of the segment's day in the display timezone.
+	Day time.Time
+
+	// Start / End are the occurrence span clipped to [Day, next day).
+	Start time.Time
+	End   time.Time
+
+	// ContinuesFromPrevious / ContinuesToNext report whether the occurrence
+	// also covers the previous / next day.
+	ContinuesFromPrevious bool
+	ContinuesToNext       bool
+
+	// DayIndex is the 0-based index of this day within the occurrence and
+	// DayCount the total number of days it covers.
+	DayIndex int
+	DayCount int
 }
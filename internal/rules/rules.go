@@ -0,0 +1,322 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"epdcal/internal/config"
+	"epdcal/internal/model"
+)
+
+// Engine applies config-driven rules to expanded occurrences. It is the
+// single place where events are classified (hidden, renamed, lane, ink), so
+// the API and every renderer see the same result.
+//
+// Rules run in two phases, each in config order:
+//
+//  1. Apply: every rule without match.conflict, per source, before dedup
+//     and conflict detection (so hidden events never count as busy).
+//  2. ApplyConflicts: the rules with match.conflict (including
+//     conflicts.highlight), once conflict detection has run over the merged
+//     occurrences of every source.
+//
+// "Later rules override earlier ones" therefore holds within a phase; a
+// conflict rule always overrides the non-conflict rules, wherever it is
+// listed.
+//
+// An Engine is immutable after New and safe for concurrent use.
+type Engine struct {
+	rules []rule
+}
+
+// rule is a compiled config.RuleConfig.
+type rule struct {
+	name string
+
+	sources    map[string]bool
+	summary    *regexp.Regexp
+	location   *regexp.Regexp
+	categories map[string]bool
+	keywords   []string
+	allDay     *bool
+	weekdays   map[time.Weekday]bool
+	conflict   *bool
+
+	action config.RuleAction
+}
+
+// New compiles the rules from cfg. The highlight_red keywords become the
+// first rule (keywords → red ink), followed by conflicts.highlight
+// (conflict → red ink) and cfg.Rules in order.
+//
+// Invalid rules (bad regexp, unknown weekday or ink) are skipped and
+// reported in the returned error; the Engine always contains the valid
+// ones, so callers may log the error and keep going.
+func New(cfg *config.Config) (*Engine, error) {
+	e := &Engine{}
+	if cfg == nil {
+		return e, nil
+	}
+
+	var errs []error
+
+	if len(cfg.HighlightRed) > 0 {
+		r, err := compile(config.RuleConfig{
+			Name:   "highlight_red",
+			Match:  config.RuleMatch{Keywords: cfg.HighlightRed},
+			Action: config.RuleAction{Ink: string(model.InkRed)},
+		})
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			e.rules = append(e.rules, r)
+		}
+	}
+
+	if cfg.Conflicts.Highlight && !cfg.Conflicts.Disable {
+		conflict := true
+		r, err := compile(config.RuleConfig{
+			Name:   "conflicts.highlight",
+			Match:  config.RuleMatch{Conflict: &conflict},
+			Action: config.RuleAction{Ink: string(model.InkRed)},
+		})
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			e.rules = append(e.rules, r)
+		}
+	}
+
+	for i, rc := range cfg.Rules {
+		if rc.Name == "" {
+			rc.Name = fmt.Sprintf("rules[%d]", i)
+		}
+		r, err := compile(rc)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		e.rules = append(e.rules, r)
+	}
+
+	return e, errors.Join(errs...)
+}
+
+// Len returns the number of active rules.
+func (e *Engine) Len() int {
+	if e == nil {
+		return 0
+	}
+	return len(e.rules)
+}
+
+// Apply runs every rule that does not match on conflict over occs and
+// returns the kept occurrences with their summary, lane and ink updated.
+// The input slice is not modified. A nil Engine returns occs unchanged.
+func (e *Engine) Apply(occs []model.Occurrence) []model.Occurrence {
+	return e.apply(occs, false)
+}
+
+// ApplyConflicts runs the deferred rules (those matching on conflict) over
+// occs once conflict detection has set Occurrence.Conflict.
+func (e *Engine) ApplyConflicts(occs []model.Occurrence) []model.Occurrence {
+	return e.apply(occs, true)
+}
+
+func (e *Engine) apply(occs []model.Occurrence, deferred bool) []model.Occurrence {
+	if e == nil || len(e.rules) == 0 {
+		return occs
+	}
+	out := make([]model.Occurrence, 0, len(occs))
+	for _, occ := range occs {
+		if classified, keep := e.classify(occ, deferred); keep {
+			out = append(out, classified)
+		}
+	}
+	return out
+}
+
+// Classify applies the non-deferred rules to a single occurrence. keep is
+// false if a matching rule hides it. Rules see the summary as rewritten by
+// earlier rules.
+func (e *Engine) Classify(occ model.Occurrence) (model.Occurrence, bool) {
+	return e.classify(occ, false)
+}
+
+func (e *Engine) classify(occ model.Occurrence, deferred bool) (model.Occurrence, bool) {
+	if e == nil {
+		return occ, true
+	}
+	for _, r := range e.rules {
+		if (r.conflict != nil) != deferred || !r.matches(occ) {
+			continue
+		}
+		a := r.action
+		if a.Hide {
+			return occ, false
+		}
+		if a.Rename != nil {
+			if r.summary != nil {
+				occ.Summary = strings.TrimSpace(r.summary.ReplaceAllString(occ.Summary, *a.Rename))
+			} else {
+				occ.Summary = *a.Rename
+			}
+		}
+		if a.MaxLength > 0 {
+			occ.Summary = truncateRunes(occ.Summary, a.MaxLength)
+		}
+		if a.AllDayLane {
+			occ.AllDayLane = true
+		}
+		if a.Ink != "" {
+			occ.Ink = model.Ink(a.Ink)
+		}
+	}
+	return occ, true
+}
+
+func (r rule) matches(occ model.Occurrence) bool {
+	if r.sources != nil && !r.sources[occ.SourceID] {
+		return false
+	}
+	if r.summary != nil && !r.summary.MatchString(occ.Summary) {
+		return false
+	}
+	if r.location != nil && !r.location.MatchString(occ.Location) {
+		return false
+	}
+	if r.allDay != nil && *r.allDay != occ.AllDay {
+		return false
+	}
+	if r.weekdays != nil && !r.weekdays[occ.Start.Weekday()] {
+		return false
+	}
+	if r.conflict != nil && *r.conflict != occ.Conflict {
+		return false
+	}
+	if r.categories != nil {
+		found := false
+		for _, c := range occ.Categories {
+			if r.categories[strings.ToLower(c)] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.keywords != nil {
+		text := strings.ToLower(occ.Summary + "\n" + occ.Description)
+		found := false
+		for _, kw := range r.keywords {
+			if strings.Contains(text, kw) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// compile validates and compiles a single rule.
+func compile(rc config.RuleConfig) (rule, error) {
+	r := rule{
+		name:     rc.Name,
+		allDay:   rc.Match.AllDay,
+		conflict: rc.Match.Conflict,
+		action:   rc.Action,
+	}
+	m := rc.Match
+
+	if len(m.Sources) > 0 {
+		r.sources = make(map[string]bool, len(m.Sources))
+		for _, s := range m.Sources {
+			r.sources[s] = true
+		}
+	}
+	if m.Summary != "" {
+		re, err := regexp.Compile(m.Summary)
+		if err != nil {
+			return rule{}, fmt.Errorf("rule %q: invalid summary regexp: %w", rc.Name, err)
+		}
+		r.summary = re
+	}
+	if m.Location != "" {
+		re, err := regexp.Compile(m.Location)
+		if err != nil {
+			return rule{}, fmt.Errorf("rule %q: invalid location regexp: %w", rc.Name, err)
+		}
+		r.location = re
+	}
+	if len(m.Categories) > 0 {
+		r.categories = make(map[string]bool, len(m.Categories))
+		for _, c := range m.Categories {
+			r.categories[strings.ToLower(strings.TrimSpace(c))] = true
+		}
+	}
+	for _, kw := range m.Keywords {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			r.keywords = append(r.keywords, strings.ToLower(kw))
+		}
+	}
+	if len(m.Keywords) > 0 && len(r.keywords) == 0 {
+		// Only blank keywords: match nothing rather than everything.
+		r.keywords = []string{}
+	}
+	if len(m.Weekdays) > 0 {
+		r.weekdays = make(map[time.Weekday]bool, len(m.Weekdays))
+		for _, wd := range m.Weekdays {
+			d, ok := parseWeekday(wd)
+			if !ok {
+				return rule{}, fmt.Errorf("rule %q: unknown weekday %q", rc.Name, wd)
+			}
+			r.weekdays[d] = true
+		}
+	}
+
+	switch model.Ink(rc.Action.Ink) {
+	case model.InkDefault, model.InkBlack, model.InkRed:
+	default:
+		return rule{}, fmt.Errorf("rule %q: unknown ink %q (want black or red)", rc.Name, rc.Action.Ink)
+	}
+	if rc.Action.MaxLength < 0 {
+		return rule{}, fmt.Errorf("rule %q: max_length must not be negative", rc.Name)
+	}
+
+	return r, nil
+}
+
+// parseWeekday accepts "mon".."sun" and full English weekday names,
+// case-insensitively.
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 3 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// truncateRunes shortens s to at most n runes, ending with "…" if cut.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(r[:n-1]) + "…"
+}
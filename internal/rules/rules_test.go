@@ -0,0 +1,220 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"epdcal/internal/config"
+	"epdcal/internal/model"
+)
+
+// TestConflictRulesRunInSecondPhase pins the documented order: rules with
+// match.conflict run after the others, whatever their position in the list,
+// and keep config order among themselves.
+func TestConflictRulesRunInSecondPhase(t *testing.T) {
+	yes := true
+	e, err := New(&config.Config{Rules: []config.RuleConfig{
+		{Name: "conflict-red", Match: config.RuleMatch{Conflict: &yes}, Action: config.RuleAction{Ink: "red"}},
+		{Name: "standup-black", Match: config.RuleMatch{Summary: "Standup"}, Action: config.RuleAction{Ink: "black"}},
+		{Name: "hide-focus", Match: config.RuleMatch{Summary: "^Focus"}, Action: config.RuleAction{Hide: true}},
+		{Name: "review-black", Match: config.RuleMatch{Summary: "Review", Conflict: &yes}, Action: config.RuleAction{Ink: "black"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	occs := e.Apply([]model.Occurrence{
+		{UID: "standup", Summary: "Standup"},
+		{UID: "focus", Summary: "Focus time"},
+		{UID: "review", Summary: "Review"},
+	})
+	if len(occs) != 2 {
+		t.Fatalf("phase 1 kept %d occurrences, want 2 (focus hidden)", len(occs))
+	}
+	if occs[0].Ink != model.InkBlack || occs[1].Ink != model.InkDefault {
+		t.Errorf("phase 1 inks: %q, %q; want black, default", occs[0].Ink, occs[1].Ink)
+	}
+
+	for i := range occs {
+		occs[i].Conflict = true
+	}
+	occs = e.ApplyConflicts(occs)
+	if occs[0].Ink != model.InkRed {
+		t.Errorf("standup: ink %q, want red (conflict rule listed first still wins)", occs[0].Ink)
+	}
+	if occs[1].Ink != model.InkBlack {
+		t.Errorf("review: ink %q, want black (later conflict rule wins)", occs[1].Ink)
+	}
+}
+
+func mustNew(t *testing.T, cfg *config.Config) *Engine {
+	t.Helper()
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func ptr(s string) *string { return &s }
+
+func TestHide(t *testing.T) {
+	e := mustNew(t, &config.Config{Rules: []config.RuleConfig{
+		{Match: config.RuleMatch{Summary: "(?i)^focus", Sources: []string{"work"}}, Action: config.RuleAction{Hide: true}},
+	}})
+
+	occs := e.Apply([]model.Occurrence{
+		{UID: "a", SourceID: "work", Summary: "Focus time"},
+		{UID: "b", SourceID: "personal", Summary: "Focus time"},
+		{UID: "c", SourceID: "work", Summary: "Standup"},
+	})
+	if len(occs) != 2 || occs[0].UID != "b" || occs[1].UID != "c" {
+		t.Errorf("kept %v, want b and c", occs)
+	}
+}
+
+func TestRename(t *testing.T) {
+	empty, short := "", "Busy"
+	tests := []struct {
+		name    string
+		rule    config.RuleConfig
+		summary string
+		want    string
+	}{
+		{
+			name:    "strip prefix",
+			rule:    config.RuleConfig{Match: config.RuleMatch{Summary: `^\[EXTERNAL\]\s*`}, Action: config.RuleAction{Rename: &empty}},
+			summary: "[EXTERNAL] Vendor sync",
+			want:    "Vendor sync",
+		},
+		{
+			name:    "template with groups",
+			rule:    config.RuleConfig{Match: config.RuleMatch{Summary: `^(\w+) / (\w+)$`}, Action: config.RuleAction{Rename: ptr("$2 ↔ $1")}},
+			summary: "Alice / Bob",
+			want:    "Bob ↔ Alice",
+		},
+		{
+			name:    "no summary match leaves it alone",
+			rule:    config.RuleConfig{Match: config.RuleMatch{Summary: `^\[EXTERNAL\]\s*`}, Action: config.RuleAction{Rename: &empty}},
+			summary: "Internal sync",
+			want:    "Internal sync",
+		},
+		{
+			name:    "without summary regexp it replaces",
+			rule:    config.RuleConfig{Match: config.RuleMatch{Sources: []string{"work"}}, Action: config.RuleAction{Rename: &short}},
+			summary: "1:1 with manager",
+			want:    "Busy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := mustNew(t, &config.Config{Rules: []config.RuleConfig{tt.rule}})
+			occ, keep := e.Classify(model.Occurrence{SourceID: "work", Summary: tt.summary})
+			if !keep || occ.Summary != tt.want {
+				t.Errorf("summary %q (keep %v), want %q", occ.Summary, keep, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightRedKeywords(t *testing.T) {
+	e := mustNew(t, &config.Config{HighlightRed: []string{"Deadline", " ", "시험"}})
+
+	tests := []struct {
+		occ  model.Occurrence
+		want model.Ink
+	}{
+		{model.Occurrence{Summary: "Project DEADLINE"}, model.InkRed},
+		{model.Occurrence{Summary: "Review", Description: "final deadline for comments"}, model.InkRed},
+		{model.Occurrence{Summary: "중간시험"}, model.InkRed},
+		{model.Occurrence{Summary: "Lunch", Description: "with the team"}, model.InkDefault},
+	}
+	for _, tt := range tests {
+		occ, _ := e.Classify(tt.occ)
+		if occ.Ink != tt.want {
+			t.Errorf("%q / %q: ink %q, want %q", tt.occ.Summary, tt.occ.Description, occ.Ink, tt.want)
+		}
+	}
+
+	// Blank keywords alone must not highlight everything.
+	e = mustNew(t, &config.Config{HighlightRed: []string{" "}})
+	if occ, _ := e.Classify(model.Occurrence{Summary: "Lunch"}); occ.Ink != model.InkDefault {
+		t.Errorf("blank keyword highlighted %q", occ.Summary)
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	tests := []struct {
+		max     int
+		summary string
+		want    string
+	}{
+		{10, "Short", "Short"},
+		{10, "Exactly10!", "Exactly10!"},
+		{10, "Quarterly planning review", "Quarterly…"},
+		{4, "분기 계획 회의", "분기 …"},
+		{1, "Standup", "…"},
+	}
+	for _, tt := range tests {
+		e := mustNew(t, &config.Config{Rules: []config.RuleConfig{{Action: config.RuleAction{MaxLength: tt.max}}}})
+		if occ, _ := e.Classify(model.Occurrence{Summary: tt.summary}); occ.Summary != tt.want {
+			t.Errorf("max %d %q: got %q, want %q", tt.max, tt.summary, occ.Summary, tt.want)
+		}
+	}
+}
+
+func TestWeekdays(t *testing.T) {
+	e := mustNew(t, &config.Config{Rules: []config.RuleConfig{
+		{Match: config.RuleMatch{Weekdays: []string{"sat", "Sunday"}}, Action: config.RuleAction{AllDayLane: true}},
+	}})
+
+	loc := time.FixedZone("KST", 9*3600)
+	tests := []struct {
+		start time.Time
+		want  bool
+	}{
+		{time.Date(2025, 3, 8, 10, 0, 0, 0, loc), true},   // Saturday
+		{time.Date(2025, 3, 9, 10, 0, 0, 0, loc), true},   // Sunday
+		{time.Date(2025, 3, 10, 10, 0, 0, 0, loc), false}, // Monday
+		// Monday 08:00 KST is still Sunday in UTC; the display zone counts.
+		{time.Date(2025, 3, 10, 8, 0, 0, 0, loc), false},
+	}
+	for _, tt := range tests {
+		occ, _ := e.Classify(model.Occurrence{Summary: "x", Start: tt.start})
+		if occ.AllDayLane != tt.want {
+			t.Errorf("%s: matched %v, want %v", tt.start.Format("Mon 15:04"), occ.AllDayLane, tt.want)
+		}
+	}
+}
+
+func TestNewSkipsInvalidRules(t *testing.T) {
+	e, err := New(&config.Config{
+		HighlightRed: []string{"deadline"},
+		Rules: []config.RuleConfig{
+			{Name: "bad-regexp", Match: config.RuleMatch{Summary: "("}, Action: config.RuleAction{Hide: true}},
+			{Name: "hide-focus", Match: config.RuleMatch{Summary: "^Focus"}, Action: config.RuleAction{Hide: true}},
+			{Match: config.RuleMatch{Weekdays: []string{"funday"}}, Action: config.RuleAction{Ink: "red"}},
+			{Name: "bad-ink", Action: config.RuleAction{Ink: "blue"}},
+		},
+	})
+	if err == nil {
+		t.Fatal("New returned no error for invalid rules")
+	}
+	for _, want := range []string{`"bad-regexp"`, `"rules[2]"`, `"bad-ink"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if e == nil || e.Len() != 2 {
+		t.Fatalf("engine has %d rules, want 2 (highlight_red and hide-focus)", e.Len())
+	}
+
+	occs := e.Apply([]model.Occurrence{
+		{UID: "focus", Summary: "Focus time"},
+		{UID: "deadline", Summary: "Tax deadline"},
+	})
+	if len(occs) != 1 || occs[0].UID != "deadline" || occs[0].Ink != model.InkRed {
+		t.Errorf("partial engine result %v, want only the red deadline", occs)
+	}
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"epdcal/internal/ics"
+	"epdcal/internal/model"
+)
+
+// Snapshot is the immutable result of one refresh cycle. Readers must not
+// modify the slices.
+type Snapshot struct {
+	// Generation increases by one on every Publish, starting at 1. The
+	// capture step uses it to verify that the rendered page shows data from
+	// the cycle it just ran.
+	Generation uint64
+
+	UpdatedAt time.Time
+
+	// RangeStart / RangeEnd is the window the occurrences were expanded for.
+	RangeStart time.Time
+	RangeEnd   time.Time
+	Location   *time.Location
+
+	Occurrences   []model.Occurrence
+	TruncatedUIDs []string
+
+	// SourceErrors maps source IDs whose fetch/parse failed in this cycle to
+	// the error message. Their occurrences may be carried over from the
+	// previous generation.
+	SourceErrors map[string]string
+
+	// Tasks are the VTODOs of every ICS source, expanded for the cycle's
+	// window (recurring ones collapsed to their current instance).
+	Tasks []model.Task
+
+	// Sources maps ICS source IDs to what this cycle recorded for them
+	// (served by /api/sources/{id}/diagnostics).
+	Sources map[string]SourceStatus
+}
+
+// SourceStatus is the per-source outcome of a refresh cycle.
+type SourceStatus struct {
+	// FromCache is set when the cached body was used (304 or fetch failure).
+	FromCache bool
+	// ExpansionCached is set when the parse/expand result was reused.
+	ExpansionCached bool
+	// Occurrences is the number of occurrences the source contributed
+	// before dedup.
+	Occurrences int
+	// Diagnostics are the fetch, parse and expand problems of the cycle.
+	Diagnostics []ics.Diagnostic
+
+	// Expanded holds the source's occurrences as expanded, before rules,
+	// dedup and conflict detection. A failed fetch carries them over from
+	// the previous generation so that they are classified again with the
+	// current rules instead of keeping stale ink or lanes.
+	Expanded []model.Occurrence
+}
+
+// Store is the in-process event repository shared between the refresh loop
+// (writer) and the HTTP server (reader).
+//
+// refresh cycle 이 fetch/parse/expand 결과를 Snapshot 으로 한 번에 교체하고,
+// /api/events 는 네트워크 접근 없이 현재 Snapshot 만 읽는다.
+// It is safe for concurrent use; readers never block writers.
+type Store struct {
+	mu  sync.Mutex // serializes Publish so generations are strictly ordered
+	cur atomic.Pointer[Snapshot]
+}
+
+// New creates an empty Store. Current returns nil until the first Publish.
+func New() *Store {
+	return &Store{}
+}
+
+// Publish atomically replaces the current snapshot and returns the new
+// generation. Generation is assigned by the store; UpdatedAt defaults to now.
+func (s *Store) Publish(snap Snapshot) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var gen uint64 = 1
+	if prev := s.cur.Load(); prev != nil {
+		gen = prev.Generation + 1
+	}
+	snap.Generation = gen
+	if snap.UpdatedAt.IsZero() {
+		snap.UpdatedAt = time.Now()
+	}
+	s.cur.Store(&snap)
+	return gen
+}
+
+// Current returns the latest snapshot, or nil if nothing was published yet.
+func (s *Store) Current() *Snapshot {
+	return s.cur.Load()
+}
+
+// Generation returns the latest generation, or 0 if nothing was published.
+func (s *Store) Generation() uint64 {
+	if snap := s.cur.Load(); snap != nil {
+		return snap.Generation
+	}
+	return 0
+}
@@ -0,0 +1,241 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"epdcal/internal/config"
+	"epdcal/internal/eventbus"
+	appLog "epdcal/internal/log"
+	"epdcal/internal/rules"
+)
+
+// Actions are the pipeline operations exposed through the admin API. They
+// are provided by main; a nil func makes its endpoint answer 501.
+type Actions struct {
+	// Refresh runs fetch + render + display.
+	Refresh func(ctx context.Context) error
+	// Render runs fetch + render without updating the panel.
+	Render func(ctx context.Context) error
+	// ClearCaches drops the ICS source and expansion caches.
+	ClearCaches func()
+	// ConfigPath is where POST /api/config saves the configuration.
+	ConfigPath string
+}
+
+// maxConfigBody bounds the POST /api/config request body.
+const maxConfigBody = 1 << 20
+
+// handleGetConfig serves GET /api/config (admin). Passwords are blanked;
+// posting an empty password back keeps the stored one.
+func (s *Server) handleGetConfig(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, redactConfig(s.cfg))
+}
+
+// handlePostConfig serves POST /api/config (admin): the body uses the GET
+// shape and may omit fields to leave them unchanged. The result is
+// validated, saved to the config file and announced as config_changed.
+// The running process keeps its current settings until it is restarted,
+// since the refresh loop and the server read the config without locking.
+func (s *Server) handlePostConfig(w http.ResponseWriter, r *http.Request) {
+	if s.actions == nil || s.actions.ConfigPath == "" {
+		writeError(w, http.StatusNotImplemented, "config saving is not available")
+		return
+	}
+
+	// Decode onto a deep copy of the current config so that fields the
+	// client does not send (the config page only edits a subset) are kept.
+	// A plain struct copy would let the decoder write into slices shared
+	// with the live config.
+	var next config.Config
+	if err := cloneConfig(s.cfg, &next); err != nil {
+		appLog.Error("failed to copy config", err)
+		writeError(w, http.StatusInternalServerError, "failed to copy config")
+		return
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigBody))
+	if err := dec.Decode(&next); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid config JSON: "+err.Error())
+		return
+	}
+	keepSecrets(&next, s.cfg)
+	next.Normalize()
+	if err := validateConfig(&next); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := config.Save(s.actions.ConfigPath, &next); err != nil {
+		appLog.Error("failed to save config", err, "config_path", s.actions.ConfigPath)
+		writeError(w, http.StatusInternalServerError, "failed to save config")
+		return
+	}
+
+	appLog.Info("config saved via API", "config_path", s.actions.ConfigPath, "user", requestUser(r))
+	s.bus.Publish(eventbus.ConfigChanged, map[string]any{"restart_required": true})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"saved":            true,
+		"restart_required": true,
+	})
+}
+
+// cloneConfig deep-copies src into dst through its JSON form.
+func cloneConfig(src, dst *config.Config) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// validateConfig rejects settings that would fail at startup.
+func validateConfig(c *config.Config) error {
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", c.Timezone)
+	}
+	if _, err := cron.ParseStandard(c.RefreshCron); err != nil {
+		return fmt.Errorf("invalid refresh schedule %q: %v", c.RefreshCron, err)
+	}
+	if _, err := rules.New(c); err != nil {
+		return fmt.Errorf("invalid rules: %v", err)
+	}
+	ids := make(map[string]bool, len(c.ICS))
+	for _, src := range c.ICS {
+		id := src.SourceID()
+		if ids[id] {
+			return fmt.Errorf("duplicate source id %q", id)
+		}
+		ids[id] = true
+	}
+	return nil
+}
+
+// redactConfig returns a copy of c with passwords and tokens blanked.
+func redactConfig(c *config.Config) config.Config {
+	out := *c
+	if c.BasicAuth != nil {
+		ba := *c.BasicAuth
+		ba.Password = ""
+		out.BasicAuth = &ba
+	}
+	out.Users = make([]config.UserConfig, len(c.Users))
+	for i, u := range c.Users {
+		u.Password = ""
+		out.Users[i] = u
+	}
+	out.Metrics.BearerToken = ""
+	out.Export.Tokens = make([]config.ExportToken, len(c.Export.Tokens))
+	for i, t := range c.Export.Tokens {
+		t.Token = ""
+		out.Export.Tokens[i] = t
+	}
+	return out
+}
+
+// keepSecrets restores blank passwords and tokens in next from cur,
+// matching accounts by username and tokens by name.
+func keepSecrets(next, cur *config.Config) {
+	if next.BasicAuth != nil && next.BasicAuth.Password == "" && cur.BasicAuth != nil &&
+		next.BasicAuth.Username == cur.BasicAuth.Username {
+		next.BasicAuth.Password = cur.BasicAuth.Password
+	}
+	for i := range next.Users {
+		if next.Users[i].Password != "" {
+			continue
+		}
+		for _, u := range cur.Users {
+			if u.Username == next.Users[i].Username {
+				next.Users[i].Password = u.Password
+				break
+			}
+		}
+	}
+	if next.Metrics.BearerToken == "" {
+		next.Metrics.BearerToken = cur.Metrics.BearerToken
+	}
+	for i := range next.Export.Tokens {
+		if next.Export.Tokens[i].Token != "" {
+			continue
+		}
+		for _, t := range cur.Export.Tokens {
+			if t.Name == next.Export.Tokens[i].Name {
+				next.Export.Tokens[i].Token = t.Token
+				break
+			}
+		}
+	}
+}
+
+// handleRefresh serves POST /api/refresh (admin): fetch + render + display
+// in the background. Progress is reported on /api/stream.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var fn func(context.Context) error
+	if s.actions != nil {
+		fn = s.actions.Refresh
+	}
+	s.startJob(w, r, "refresh", fn)
+}
+
+// handleRender serves POST /api/render (admin): fetch + render only, to
+// update preview.png without touching the panel.
+func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
+	var fn func(context.Context) error
+	if s.actions != nil {
+		fn = s.actions.Render
+	}
+	s.startJob(w, r, "render", fn)
+}
+
+var errJobRunning = errors.New("another refresh or render is already running")
+
+// startJob runs fn detached from the request (it outlives the response and
+// is cancelled on shutdown) and answers 202, or 409 while a job triggered
+// through the API is still running.
+func (s *Server) startJob(w http.ResponseWriter, r *http.Request, name string, fn func(context.Context) error) {
+	if fn == nil {
+		writeError(w, http.StatusNotImplemented, name+" is not available")
+		return
+	}
+	if !s.jobRunning.CompareAndSwap(false, true) {
+		writeError(w, http.StatusConflict, errJobRunning.Error())
+		return
+	}
+
+	appLog.Info("manual job triggered via API", "job", name, "user", requestUser(r))
+
+	go func() {
+		defer s.jobRunning.Store(false)
+		if err := fn(s.baseCtx); err != nil {
+			appLog.Error("manual job failed", err, "job", name)
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, map[string]any{"started": name})
+}
+
+// handleCacheClear serves POST /api/cache/clear (admin): drops the rendered
+// response cache, the battery cache and the ICS source/expansion caches.
+// The next refresh parses every feed again.
+func (s *Server) handleCacheClear(w http.ResponseWriter, r *http.Request) {
+	cleared := []string{"responses", "battery"}
+	s.eventsCache.clear()
+
+	s.batteryMu.Lock()
+	s.batteryCache = nil
+	s.batteryMu.Unlock()
+
+	if s.actions != nil && s.actions.ClearCaches != nil {
+		s.actions.ClearCaches()
+		cleared = append(cleared, "ics")
+	}
+
+	appLog.Info("caches cleared via API", "cleared", cleared, "user", requestUser(r))
+	writeJSON(w, http.StatusOK, map[string]any{"cleared": cleared})
+}
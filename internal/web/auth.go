@@ -0,0 +1,196 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"epdcal/internal/apitoken"
+	"epdcal/internal/config"
+	appLog "epdcal/internal/log"
+)
+
+type principalKey struct{}
+
+// principal is the authenticated caller: a Basic Auth account or an API
+// token (token != nil).
+type principal struct {
+	name  string
+	role  string
+	token *apitoken.Token
+}
+
+// requestPrincipal returns the caller authenticated for r, or nil when
+// authentication is off.
+func requestPrincipal(r *http.Request) *principal {
+	p, _ := r.Context().Value(principalKey{}).(*principal)
+	return p
+}
+
+// requestRole returns the role authenticated for r. Without any configured
+// account every request is admin, as before roles existed.
+func requestRole(r *http.Request) string {
+	if p := requestPrincipal(r); p != nil {
+		return p.role
+	}
+	return config.RoleAdmin
+}
+
+// requestUser returns the account or token name for logging.
+func requestUser(r *http.Request) string {
+	if p := requestPrincipal(r); p != nil {
+		return p.name
+	}
+	return ""
+}
+
+// authEnabled reports whether any Basic Auth account is configured. API
+// tokens are only checked when it is; without accounts the API is open.
+func (s *Server) authEnabled() bool {
+	return s.cfg != nil && len(s.cfg.Accounts()) > 0
+}
+
+// isExportTokenRequest reports whether r targets exactly the export token
+// route, GET /export/{token}/calendar.ics with a single non-empty segment.
+func isExportTokenRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/export/")
+	if !ok {
+		return false
+	}
+	token, ok := strings.CutSuffix(rest, "/calendar.ics")
+	return ok && token != "" && token != "." && token != ".." && !strings.Contains(token, "/")
+}
+
+// authMiddleware authenticates every request except /health (and /metrics
+// or export token URLs when they have their own auth) against basic_auth,
+// users and API tokens (`Authorization: Bearer <token>`), and records the
+// caller for requireAdmin / requireRefresh.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /health 는 항상 무인증으로 노출한다.
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// 토큰 URL(/export/<token>/calendar.ics)은 handleExportToken 이 토큰으로 인증한다.
+		// 그 경로만 정확히 통과시켜 /export/ 아래 다른 핸들러가 인증 없이 열리지 않게 한다.
+		if len(s.cfg.Export.Tokens) > 0 && isExportTokenRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// /metrics 에 별도 인증(public / bearer_token)이 설정된 경우 handleMetrics 가 처리한다.
+		if r.URL.Path == "/metrics" && s.metricsOwnAuth() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		accounts := s.cfg.Accounts()
+		if len(accounts) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.checkLockout(w, r) {
+			return
+		}
+
+		if secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			s.serveToken(w, r, strings.TrimSpace(secret), next)
+			return
+		}
+
+		u, p, ok := r.BasicAuth()
+		var matched *config.UserConfig
+		if ok {
+			// Compare against every account so that timing does not reveal
+			// which usernames exist.
+			for i := range accounts {
+				if secureCompare(u, accounts[i].Username) && secureCompare(p, accounts[i].Password) && matched == nil {
+					matched = &accounts[i]
+				}
+			}
+		}
+		if matched == nil {
+			// A request without credentials is the browser's first try, not
+			// a failed login.
+			if ok {
+				s.authFailed(r)
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="EPDCal", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		s.authSucceeded(r)
+		ctx := context.WithValue(r.Context(), principalKey{}, &principal{name: matched.Username, role: matched.Role})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// serveToken authenticates r with an API token and enforces its scopes:
+// POST /api/refresh and /api/render need trigger-refresh, everything else
+// needs read-events, and admin-only endpoints need admin (via the role).
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request, secret string, next http.Handler) {
+	tok, ok, err := s.tokens.Lookup(secret)
+	if err != nil {
+		appLog.Error("failed to read API token state", err, "path", s.tokens.Path())
+	}
+	if !ok {
+		s.authFailed(r)
+		appLog.Info("invalid API token", "path", r.URL.Path, "remote", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="EPDCal", error="invalid_token"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.authSucceeded(r)
+	appLog.Info("API token used", "token", tok.Name, "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+
+	scope := apitoken.ScopeReadEvents
+	if isRefreshRequest(r) {
+		scope = apitoken.ScopeTriggerRefresh
+	}
+	if !tok.Has(scope) {
+		appLog.Info("API token scope denied", "token", tok.Name, "path", r.URL.Path, "scope", scope)
+		writeError(w, http.StatusForbidden, "token scope "+scope+" required")
+		return
+	}
+
+	role := config.RoleViewer
+	if tok.Has(apitoken.ScopeAdmin) {
+		role = config.RoleAdmin
+	}
+	ctx := context.WithValue(r.Context(), principalKey{}, &principal{name: tok.Name, role: role, token: &tok})
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// isRefreshRequest reports whether r triggers the pipeline.
+func isRefreshRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && (r.URL.Path == "/api/refresh" || r.URL.Path == "/api/render")
+}
+
+// requireAdmin rejects requests whose account is not an admin with 403.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if requestRole(r) != config.RoleAdmin {
+			appLog.Info("admin endpoint denied", "path", r.URL.Path, "user", requestUser(r))
+			writeError(w, http.StatusForbidden, "admin role required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireRefresh is requireAdmin that also lets API tokens with the
+// trigger-refresh scope through.
+func requireRefresh(next http.HandlerFunc) http.HandlerFunc {
+	admin := requireAdmin(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p := requestPrincipal(r); p != nil && p.token != nil && p.token.Has(apitoken.ScopeTriggerRefresh) {
+			next(w, r)
+			return
+		}
+		admin(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"epdcal/internal/config"
+)
+
+func TestAuthBypassesOnlyExportTokenRoute(t *testing.T) {
+	s := &Server{
+		cfg: &config.Config{
+			BasicAuth: &config.BasicAuthConfig{Username: "admin", Password: "secret"},
+			Export:    config.ExportConfig{Tokens: []config.ExportToken{{Name: "phone", Token: "tok"}}},
+		},
+		rateLimiter: newRateLimiter(config.RateLimitConfig{}),
+	}
+	h := s.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range []struct {
+		method, path string
+		open         bool
+	}{
+		{http.MethodGet, "/export/tok/calendar.ics", true},
+		{http.MethodHead, "/export/wrong/calendar.ics", true}, // handleExportToken answers 404
+		{http.MethodPost, "/export/tok/calendar.ics", false},
+		{http.MethodGet, "/export/tok/other.ics", false},
+		{http.MethodGet, "/export/tok", false},
+		{http.MethodGet, "/export//calendar.ics", false},
+		{http.MethodGet, "/export/a/b/calendar.ics", false},
+		{http.MethodGet, "/export/../calendar.ics", false},
+		{http.MethodGet, "/export/", false},
+		{http.MethodGet, "/api/config", false},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, "http://epdcal.local/", nil)
+		req.URL.Path = tt.path
+		h.ServeHTTP(rec, req)
+		if open := rec.Code == http.StatusOK; open != tt.open {
+			t.Errorf("%s %s: status %d, want open=%v", tt.method, tt.path, rec.Code, tt.open)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"epdcal/internal/conflict"
+	"epdcal/internal/model"
+)
+
+// conflictsResponse is the JSON response shape for /api/conflicts.
+type conflictsResponse struct {
+	Conflicts  []conflictDTO `json:"conflicts"`
+	Disabled   bool          `json:"disabled,omitempty"` // conflicts.disable is set
+	RangeStart time.Time     `json:"range_start"`
+	RangeEnd   time.Time     `json:"range_end"`
+	Generation uint64        `json:"generation"`
+}
+
+// conflictDTO is a single clash between two busy occurrences.
+type conflictDTO struct {
+	Start time.Time     `json:"start"` // overlap start
+	End   time.Time     `json:"end"`   // overlap end
+	A     occurrenceDTO `json:"a"`
+	B     occurrenceDTO `json:"b"`
+}
+
+// handleConflicts lists upcoming double-bookings from the store.
+//
+// GET /api/conflicts?days=N
+//   - days: 지금부터 몇 일 이내의 충돌을 볼 것인지 (기본 horizon_days,
+//     1..maxEventsRangeDays; 그 밖의 값은 400)
+//
+// 이미 끝난 일정은 제외하고, 진행 중인 일정은 포함한다. 어떤 소스의 일정을
+// busy 로 볼지는 config 의 conflicts.sources 를 따른다.
+func (s *Server) handleConflicts(w http.ResponseWriter, r *http.Request) {
+	horizon := s.cfg.HorizonDays
+	if horizon <= 0 {
+		horizon = 7
+	}
+	days, err := parsePositiveInt("days", r.URL.Query().Get("days"), horizon, 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if days > maxEventsRangeDays {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("range too large: at most %d days", maxEventsRangeDays))
+		return
+	}
+
+	snap := s.store.Current()
+	if snap == nil {
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, "events not loaded yet")
+		return
+	}
+
+	loc := resolveLocationOrLocal(s.cfg.Timezone)
+	rangeStart := time.Now().In(loc)
+	rangeEnd := rangeStart.AddDate(0, 0, days)
+
+	resp := conflictsResponse{
+		Conflicts:  []conflictDTO{},
+		Disabled:   s.cfg.Conflicts.Disable,
+		RangeStart: rangeStart,
+		RangeEnd:   rangeEnd,
+		Generation: snap.Generation,
+	}
+	if s.cfg.Conflicts.Disable {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	occs := make([]model.Occurrence, 0, len(snap.Occurrences))
+	for _, occ := range snap.Occurrences {
+		if !occ.End.After(rangeStart) || !occ.Start.Before(rangeEnd) {
+			continue
+		}
+		occs = append(occs, occ)
+	}
+
+	for _, p := range conflict.Detect(occs, conflict.Options{Sources: s.cfg.Conflicts.Sources}) {
+		resp.Conflicts = append(resp.Conflicts, conflictDTO{
+			Start: p.Start,
+			End:   p.End,
+			A:     toOccurrenceDTO(p.A),
+			B:     toOccurrenceDTO(p.B),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,32 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"epdcal/internal/config"
+)
+
+func TestConflictsRejectsInvalidDays(t *testing.T) {
+	s := newEventsTestServer(t, &config.Config{Timezone: "UTC", HorizonDays: 7}, time.Now())
+
+	for _, tt := range []struct {
+		query string
+		code  int
+	}{
+		{"", http.StatusOK},
+		{"days=3", http.StatusOK},
+		{"days=abc", http.StatusBadRequest},
+		{"days=0", http.StatusBadRequest},
+		{"days=-2", http.StatusBadRequest},
+		{"days=401", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		s.handleConflicts(rec, httptest.NewRequest(http.MethodGet, "/api/conflicts?"+tt.query, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%q: status %d, want %d", tt.query, rec.Code, tt.code)
+		}
+	}
+}
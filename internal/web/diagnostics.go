@@ -0,0 +1,81 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"epdcal/internal/ics"
+	"epdcal/internal/store"
+)
+
+// diagnosticsResponse is the JSON response shape for
+// /api/sources/{id}/diagnostics.
+type diagnosticsResponse struct {
+	SourceID        string           `json:"source_id"`
+	Generation      uint64           `json:"generation"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	FromCache       bool             `json:"from_cache"`
+	ExpansionCached bool             `json:"expansion_cached"`
+	OccurrenceCount int              `json:"occurrence_count"`
+	Errors          int              `json:"errors"`
+	Warnings        int              `json:"warnings"`
+	RangeStart      time.Time        `json:"range_start"`
+	RangeEnd        time.Time        `json:"range_end"`
+	Diagnostics     []ics.Diagnostic `json:"diagnostics"`
+}
+
+// handleSourceDiagnostics serves the diagnostics that the last refresh
+// cycle collected for a single configured source (fetch, streamed parse
+// and expansion over the cycle's window). It never touches the network.
+//
+// GET /api/sources/{id}/diagnostics
+func (s *Server) handleSourceDiagnostics(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	found := false
+	for _, cand := range s.icsSources() {
+		if cand.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "unknown source id")
+		return
+	}
+
+	snap := s.store.Current()
+	status, ok := store.SourceStatus{}, false
+	if snap != nil {
+		status, ok = snap.Sources[id]
+	}
+	if !ok {
+		// 첫 refresh cycle 이 끝나기 전(또는 소스가 추가된 뒤 아직 돌지 않음).
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, "source not processed yet")
+		return
+	}
+
+	resp := diagnosticsResponse{
+		SourceID:        id,
+		Generation:      snap.Generation,
+		UpdatedAt:       snap.UpdatedAt,
+		FromCache:       status.FromCache,
+		ExpansionCached: status.ExpansionCached,
+		OccurrenceCount: status.Occurrences,
+		RangeStart:      snap.RangeStart,
+		RangeEnd:        snap.RangeEnd,
+		Diagnostics:     []ics.Diagnostic{},
+	}
+	for _, d := range status.Diagnostics {
+		resp.Diagnostics = append(resp.Diagnostics, d)
+		switch d.Severity {
+		case ics.SeverityError:
+			resp.Errors++
+		case ics.SeverityWarning:
+			resp.Warnings++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
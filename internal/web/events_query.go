@@ -0,0 +1,350 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"epdcal/internal/ics"
+	"epdcal/internal/model"
+)
+
+// maxEventsRangeDays bounds the window a single /api/events request may ask
+// for.
+const maxEventsRangeDays = 400
+
+// eventsQuery is a validated /api/events request.
+type eventsQuery struct {
+	rangeStart time.Time
+	rangeEnd   time.Time
+
+	// window describes how the range was chosen, for the cache key. For
+	// now-relative windows (days/backfill, agenda without start) it holds
+	// the parameters rather than the computed range so that repeated
+	// requests share an entry.
+	window string
+
+	// relative is set when the range depends on the current time (days,
+	// backfill, view without start, the default window). Such responses
+	// change without a new generation, so they carry no Last-Modified and
+	// are validated by ETag only.
+	relative bool
+
+	sources  map[string]bool // nil = every source
+	allDay   string          // "", "only" or "exclude"
+	text     string          // lower-cased q
+	segments bool
+	layout   bool
+	capacity int
+
+	// format is the response representation: formatJSON (the epdcal shape),
+	// formatJCal or formatJSCalendar. Set by ?format= or, failing that, by
+	// negotiate from the Accept header.
+	format string
+}
+
+// /api/events response formats.
+const (
+	formatJSON       = "json"
+	formatJCal       = "jcal"
+	formatJSCalendar = "jscalendar"
+)
+
+// parseEventsQuery validates the /api/events query parameters. Every
+// error is meant to be returned to the client as a 400.
+func (s *Server) parseEventsQuery(q url.Values, loc *time.Location, now time.Time) (eventsQuery, error) {
+	var eq eventsQuery
+	var err error
+
+	horizon := s.cfg.HorizonDays
+	if horizon <= 0 {
+		horizon = 7
+	}
+
+	rawDays, rawBackfill := q.Get("days"), q.Get("backfill")
+	rawStart, rawEnd, view := q.Get("start"), q.Get("end"), strings.ToLower(q.Get("view"))
+	rawWeeks := q.Get("weeks")
+
+	if rawWeeks != "" && view != "week" {
+		return eq, errors.New("weeks can only be used with view=week")
+	}
+
+	if (rawDays != "" || rawBackfill != "") && (rawStart != "" || rawEnd != "" || view != "") {
+		return eq, errors.New("days/backfill cannot be combined with start, end or view")
+	}
+
+	switch {
+	case rawDays != "" || rawBackfill != "":
+		// Legacy now-relative window.
+		days, err := parsePositiveInt("days", rawDays, horizon, 1)
+		if err != nil {
+			return eq, err
+		}
+		backfill, err := parsePositiveInt("backfill", rawBackfill, 1, 0)
+		if err != nil {
+			return eq, err
+		}
+		eq.rangeStart = now.AddDate(0, 0, -backfill)
+		eq.rangeEnd = now.AddDate(0, 0, days)
+		eq.window = fmt.Sprintf("days=%d|backfill=%d", days, backfill)
+		eq.relative = true
+
+	case view != "":
+		if rawEnd != "" {
+			return eq, errors.New("end cannot be combined with view; use start to choose the day the view contains")
+		}
+		anchor := startOfDay(now)
+		relative := true
+		if rawStart != "" {
+			if anchor, _, err = parseTimeParam("start", rawStart, loc); err != nil {
+				return eq, err
+			}
+			relative = false
+		}
+		eq.relative = relative
+		switch view {
+		case "day":
+			eq.rangeStart = startOfDay(anchor)
+			eq.rangeEnd = eq.rangeStart.AddDate(0, 0, 1)
+		case "week":
+			// weeks=N: N whole weeks from the week containing the anchor
+			// (e.g. the Web UI's five-week grid).
+			weeks, err := parsePositiveInt("weeks", rawWeeks, 1, 1)
+			if err != nil {
+				return eq, err
+			}
+			eq.rangeStart = startOfWeek(anchor, loc, s.cfg.WeekStart)
+			eq.rangeEnd = eq.rangeStart.AddDate(0, 0, 7*weeks)
+		case "month":
+			// Whole weeks covering the month, as a month grid shows them.
+			first := time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, loc)
+			last := first.AddDate(0, 1, -1)
+			eq.rangeStart = startOfWeek(first, loc, s.cfg.WeekStart)
+			eq.rangeEnd = startOfWeek(last, loc, s.cfg.WeekStart).AddDate(0, 0, 7)
+		case "agenda":
+			eq.rangeStart = anchor
+			if relative {
+				eq.rangeStart = now
+			}
+			eq.rangeEnd = eq.rangeStart.AddDate(0, 0, horizon)
+			if relative {
+				eq.window = fmt.Sprintf("view=agenda|horizon=%d", horizon)
+			}
+		default:
+			return eq, fmt.Errorf("invalid view %q: must be day, week, month or agenda", view)
+		}
+
+	case rawStart != "" || rawEnd != "":
+		if rawStart == "" {
+			return eq, errors.New("end requires start")
+		}
+		if eq.rangeStart, _, err = parseTimeParam("start", rawStart, loc); err != nil {
+			return eq, err
+		}
+		eq.rangeEnd = eq.rangeStart.AddDate(0, 0, horizon)
+		if rawEnd != "" {
+			end, dateOnly, err := parseTimeParam("end", rawEnd, loc)
+			if err != nil {
+				return eq, err
+			}
+			if dateOnly {
+				// A date end is inclusive: the whole day is part of the range.
+				end = end.AddDate(0, 0, 1)
+			}
+			eq.rangeEnd = end
+		}
+
+	default:
+		// 기본값: 이번 주 시작(week_start 설정에 따라 일/월)부터 오늘 + horizon_days
+		// 까지를 주 단위로 올림한 범위.
+		eq.rangeStart = startOfWeek(now, loc, s.cfg.WeekStart)
+		eq.rangeEnd = startOfWeek(startOfDay(now).AddDate(0, 0, horizon-1), loc, s.cfg.WeekStart).AddDate(0, 0, 7)
+		eq.relative = true
+	}
+
+	if !eq.rangeEnd.After(eq.rangeStart) {
+		return eq, errors.New("end must be after start")
+	}
+	if eq.rangeEnd.Sub(eq.rangeStart) > maxEventsRangeDays*24*time.Hour {
+		return eq, fmt.Errorf("range too large: at most %d days", maxEventsRangeDays)
+	}
+	if eq.window == "" {
+		eq.window = eq.rangeStart.Format(time.RFC3339) + "/" + eq.rangeEnd.Format(time.RFC3339)
+	}
+
+	if ids := q["source"]; len(ids) > 0 {
+		known := make(map[string]bool, len(s.cfg.ICS))
+		for _, c := range s.cfg.ICS {
+			known[c.SourceID()] = true
+		}
+		eq.sources = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if !known[id] {
+				return eq, fmt.Errorf("unknown source %q", id)
+			}
+			eq.sources[id] = true
+		}
+	}
+
+	switch v := strings.ToLower(q.Get("all_day")); v {
+	case "", "only", "exclude":
+		eq.allDay = v
+	default:
+		return eq, fmt.Errorf("invalid all_day %q: must be only or exclude", v)
+	}
+
+	eq.text = strings.ToLower(strings.TrimSpace(q.Get("q")))
+
+	if eq.segments, err = parseBoolParam("segments", q.Get("segments")); err != nil {
+		return eq, err
+	}
+	if eq.layout, err = parseBoolParam("layout", q.Get("layout")); err != nil {
+		return eq, err
+	}
+	if eq.capacity, err = parsePositiveInt("capacity", q.Get("capacity"), max(s.cfg.MaxEventsPerDay, 0), 0); err != nil {
+		return eq, err
+	}
+
+	switch f := strings.ToLower(q.Get("format")); f {
+	case "", formatJSON, formatJCal, formatJSCalendar:
+		eq.format = f
+	default:
+		return eq, fmt.Errorf("invalid format %q: must be json, jcal or jscalendar", f)
+	}
+
+	return eq, nil
+}
+
+// negotiate picks the response format from the Accept header when ?format=
+// was not given (first supported media type wins, q-values are ignored)
+// and checks that the options requested fit it.
+func (eq *eventsQuery) negotiate(accept string) error {
+	if eq.format == "" {
+		eq.format = formatJSON
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			switch strings.ToLower(strings.TrimSpace(mediaType)) {
+			case ics.JCalContentType:
+				eq.format = formatJCal
+			case ics.JSCalendarContentType:
+				eq.format = formatJSCalendar
+			case "application/json":
+			default:
+				continue
+			}
+			break
+		}
+	}
+	if eq.format != formatJSON && (eq.segments || eq.layout) {
+		return fmt.Errorf("segments and layout are only available with format=json")
+	}
+	return nil
+}
+
+// lastModified returns the Last-Modified time of a response built from a
+// snapshot updated at updatedAt: zero (no header) for now-relative windows,
+// whose content moves with the clock rather than with the store.
+func (eq eventsQuery) lastModified(updatedAt time.Time) time.Time {
+	if eq.relative {
+		return time.Time{}
+	}
+	return updatedAt
+}
+
+// cacheKey returns the normalized key for the response cache.
+func (eq eventsQuery) cacheKey(loc *time.Location, weekStart string, generation uint64) string {
+	sources := make([]string, 0, len(eq.sources))
+	for id := range eq.sources {
+		sources = append(sources, id)
+	}
+	sort.Strings(sources)
+	return fmt.Sprintf("%s|sources=%q|all_day=%s|q=%q|segments=%t|layout=%t|capacity=%d|format=%s|tz=%s|week=%s|gen=%d",
+		eq.window, sources, eq.allDay, eq.text, eq.segments, eq.layout, eq.capacity, eq.format,
+		loc.String(), weekStart, generation)
+}
+
+// matches applies the range and filter parameters to occ. The range is
+// half-open, so an all-day event of the next day is not part of a day
+// view; zero-length occurrences at rangeStart are kept.
+func (eq eventsQuery) matches(occ model.Occurrence) bool {
+	if !occ.Start.Before(eq.rangeEnd) {
+		return false
+	}
+	if !occ.End.After(eq.rangeStart) && occ.Start.Before(eq.rangeStart) {
+		return false
+	}
+	if eq.sources != nil && !eq.sources[occ.SourceID] {
+		found := false
+		for _, id := range occ.SourceIDs {
+			if eq.sources[id] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	switch eq.allDay {
+	case "only":
+		if !occ.AllDay {
+			return false
+		}
+	case "exclude":
+		if occ.AllDay {
+			return false
+		}
+	}
+	if eq.text != "" {
+		text := strings.ToLower(occ.Summary + "\n" + occ.Description + "\n" + occ.Location)
+		if !strings.Contains(text, eq.text) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTimeParam accepts a date (YYYY-MM-DD, 00:00 in loc) or an RFC 3339
+// timestamp. dateOnly reports which form was used.
+func parseTimeParam(name, raw string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(loc), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid %s %q: want YYYY-MM-DD or RFC 3339", name, raw)
+}
+
+// parsePositiveInt parses an integer parameter that must be at least lo;
+// an empty value yields def.
+func parsePositiveInt(name, raw string, def, lo int) (int, error) {
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < lo {
+		return 0, fmt.Errorf("invalid %s %q: must be an integer >= %d", name, raw, lo)
+	}
+	return n, nil
+}
+
+func parseBoolParam(name, raw string) (bool, error) {
+	if raw == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: must be true/false or 1/0", name, raw)
+	}
+	return b, nil
+}
+
+// startOfDay returns 00:00 of t's date in t's location.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
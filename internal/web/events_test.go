@@ -0,0 +1,128 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"epdcal/internal/config"
+	"epdcal/internal/store"
+)
+
+// newEventsTestServer returns a Server with just what handleEvents needs and
+// a published snapshot updated at updatedAt.
+func newEventsTestServer(t *testing.T, cfg *config.Config, updatedAt time.Time) *Server {
+	t.Helper()
+	st := store.New()
+	st.Publish(store.Snapshot{UpdatedAt: updatedAt, Location: time.UTC})
+	return &Server{cfg: cfg, store: st, eventsCache: newResponseCache(eventsCacheSize, eventsCacheTTL)}
+}
+
+func TestEventsLastModifiedOnlyForFixedWindows(t *testing.T) {
+	updatedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	s := newEventsTestServer(t, &config.Config{Timezone: "UTC", HorizonDays: 7}, updatedAt)
+	ims := time.Now().UTC().Format(http.TimeFormat)
+
+	for _, tt := range []struct {
+		query        string
+		lastModified bool
+	}{
+		{"", false},
+		{"days=3", false},
+		{"view=agenda", false},
+		{"view=week", false},
+		{"view=agenda&start=2025-01-06", true},
+		{"start=2025-01-06&end=2025-01-12", true},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/events?"+tt.query, nil)
+		req.Header.Set("If-Modified-Since", ims)
+		rec := httptest.NewRecorder()
+		s.handleEvents(rec, req)
+
+		got := rec.Header().Get("Last-Modified")
+		if tt.lastModified {
+			if got == "" || rec.Code != http.StatusNotModified {
+				t.Errorf("%q: Last-Modified %q, status %d; want the snapshot time and 304", tt.query, got, rec.Code)
+			}
+			continue
+		}
+		if got != "" || rec.Code != http.StatusOK {
+			t.Errorf("%q: Last-Modified %q, status %d; want none and 200 for a now-relative window", tt.query, got, rec.Code)
+		}
+		if rec.Header().Get("ETag") == "" {
+			t.Errorf("%q: missing ETag", tt.query)
+		}
+	}
+}
+
+func TestEventsDefaultWindowFollowsHorizon(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, loc) // Wednesday
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, loc)
+
+	for _, tt := range []struct {
+		horizon int
+		query   url.Values
+		end     time.Time
+	}{
+		// Today + 7 days ends on Tuesday of the next week: two whole weeks.
+		{7, nil, monday.AddDate(0, 0, 14)},
+		{30, nil, monday.AddDate(0, 0, 35)},
+		{7, url.Values{"view": {"week"}, "weeks": {"5"}}, monday.AddDate(0, 0, 35)},
+	} {
+		s := &Server{cfg: &config.Config{HorizonDays: tt.horizon, WeekStart: "monday"}}
+		eq, err := s.parseEventsQuery(tt.query, loc, now)
+		if err != nil {
+			t.Fatalf("horizon %d %v: %v", tt.horizon, tt.query, err)
+		}
+		if !eq.rangeStart.Equal(monday) || !eq.rangeEnd.Equal(tt.end) {
+			t.Errorf("horizon %d %v: range %s – %s, want %s – %s", tt.horizon, tt.query, eq.rangeStart, eq.rangeEnd, monday, tt.end)
+		}
+	}
+
+	s := &Server{cfg: &config.Config{HorizonDays: 7}}
+	if _, err := s.parseEventsQuery(url.Values{"weeks": {"2"}}, loc, now); err == nil {
+		t.Error("weeks without view=week: expected an error")
+	}
+}
+
+func TestEventsReportsDataRange(t *testing.T) {
+	st := store.New()
+	windowStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC)
+	st.Publish(store.Snapshot{UpdatedAt: windowStart, Location: time.UTC, RangeStart: windowStart, RangeEnd: windowEnd})
+	s := &Server{cfg: &config.Config{Timezone: "UTC", HorizonDays: 7}, store: st, eventsCache: newResponseCache(eventsCacheSize, eventsCacheTTL)}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleEvents(rec, httptest.NewRequest(http.MethodGet, "/api/events?"+query, nil))
+		return rec
+	}
+
+	rec := get("start=2025-02-01&end=2025-02-28")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("partly covered range: status %d", rec.Code)
+	}
+	var resp eventsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.RangeTruncated || !resp.DataEnd.Equal(windowEnd) || !resp.RangeEnd.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("partly covered range: truncated %v, data_end %s, range_end %s", resp.RangeTruncated, resp.DataEnd, resp.RangeEnd)
+	}
+	if want := "2025-02-01T00:00:00Z/2025-02-15T00:00:00Z"; rec.Header().Get("X-Data-Range") != want {
+		t.Errorf("X-Data-Range %q, want %q", rec.Header().Get("X-Data-Range"), want)
+	}
+
+	// The cached response carries the header as well.
+	if rec := get("start=2025-02-01&end=2025-02-28"); rec.Header().Get("X-Data-Range") == "" {
+		t.Error("cached response: missing X-Data-Range")
+	}
+
+	if rec := get("start=2025-06-01&end=2025-06-30"); rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("range outside the window: status %d, want 416", rec.Code)
+	}
+}
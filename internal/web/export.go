@@ -0,0 +1,69 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"epdcal/internal/ics"
+	appLog "epdcal/internal/log"
+)
+
+// handleExport serves GET /calendar.ics: the merged, rule-filtered and
+// de-duplicated occurrences of the current snapshot (what the panel shows)
+// as an RFC 5545 calendar with a VTIMEZONE for the display zone.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	snap := s.store.Current()
+	if snap == nil {
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, "no refresh cycle has completed yet")
+		return
+	}
+
+	key := "calendar.ics|gen=" + strconv.FormatUint(snap.Generation, 10)
+	now := time.Now()
+	resp, ok := s.eventsCache.get(key, now)
+	if !ok {
+		name := s.cfg.Export.Name
+		if name == "" {
+			name = "epdcal"
+		}
+		var buf bytes.Buffer
+		err := ics.WriteCalendar(&buf, snap.Occurrences, ics.ExportOptions{
+			Name:       name,
+			Location:   snap.Location,
+			RangeStart: snap.RangeStart,
+			RangeEnd:   snap.RangeEnd,
+			Stamp:      snap.UpdatedAt,
+		})
+		if err != nil {
+			appLog.Error("calendar export failed", err)
+			writeError(w, http.StatusInternalServerError, "failed to build calendar")
+			return
+		}
+		resp = s.eventsCache.put(key, buf.Bytes(), snap.UpdatedAt, now)
+	}
+
+	w.Header().Set("Content-Disposition", `inline; filename="epdcal.ics"`)
+	writeCached(w, r, resp, "text/calendar; charset=utf-8")
+}
+
+// handleExportToken serves GET /export/{token}/calendar.ics for the tokens
+// in export.tokens without basic_auth. Unknown tokens get a 404 so that the
+// URL space does not reveal whether export is enabled.
+func (s *Server) handleExportToken(w http.ResponseWriter, r *http.Request) {
+	if !s.checkLockout(w, r) {
+		return
+	}
+	got := r.PathValue("token")
+	for _, t := range s.cfg.Export.Tokens {
+		if t.Token != "" && secureCompare(got, t.Token) {
+			appLog.Debug("calendar export via token", "token", t.Name)
+			s.handleExport(w, r)
+			return
+		}
+	}
+	s.authFailed(r)
+	http.NotFound(w, r)
+}
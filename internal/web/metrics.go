@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	appLog "epdcal/internal/log"
+	"epdcal/internal/metrics"
+)
+
+// metricsOwnAuth reports whether /metrics bypasses basic_auth, either
+// because it is public or because it is protected by its own bearer token.
+func (s *Server) metricsOwnAuth() bool {
+	return s.cfg.Metrics.Public || s.cfg.Metrics.BearerToken != ""
+}
+
+// handleMetrics serves GET /metrics in Prometheus text format.
+//
+// With metrics.bearer_token set, the request must carry
+// `Authorization: Bearer <token>`; basic_auth is not consulted so that the
+// scraper never needs the UI credentials.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if token := s.cfg.Metrics.BearerToken; token != "" && !s.cfg.Metrics.Public {
+		if !s.checkLockout(w, r) {
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !secureCompare(strings.TrimSpace(got), token) {
+			if ok {
+				s.authFailed(r)
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="EPDCal metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// Battery gauges are otherwise only updated when the UI asks for them;
+	// refresh them (through the 30s cache) so a scrape never sees stale data.
+	if _, err := s.readBattery(r.Context()); err != nil {
+		appLog.Debug("battery unavailable for metrics", "error", err.Error())
+	}
+
+	metrics.Default.Handler().ServeHTTP(w, r)
+}
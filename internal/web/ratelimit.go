@@ -0,0 +1,282 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"epdcal/internal/config"
+	appLog "epdcal/internal/log"
+)
+
+// Defaults for config.RateLimitConfig zero values.
+const (
+	defaultMaxAuthFailures   = 5
+	defaultLockout           = time.Minute
+	defaultMaxLockout        = time.Hour
+	defaultRequestsPerMinute = 30
+	defaultBurst             = 10
+
+	// rateStatePrune is how often idle per-IP entries are dropped.
+	rateStatePrune = 5 * time.Minute
+)
+
+// clientIP returns the host part of r.RemoteAddr. X-Forwarded-For is not
+// trusted: behind a reverse proxy on the same host every client is
+// loopback and shares one bucket.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// CaptureHeader carries CaptureSecret on every request of the chromium
+// capture, which exempts it from the lockout and rate limits.
+const CaptureHeader = "X-Epdcal-Capture"
+
+// CaptureSecret returns a random secret generated once per process. Only
+// the capture started by this process knows it, so unlike "any loopback
+// client" it cannot be borrowed by a reverse proxy on the same host.
+var CaptureSecret = sync.OnceValue(func() string {
+	return rand.Text()
+})
+
+// isCapture reports whether r is the chromium capture of this process:
+// a loopback client presenting CaptureSecret in CaptureHeader.
+func isCapture(r *http.Request) bool {
+	got := r.Header.Get(CaptureHeader)
+	if got == "" {
+		return false
+	}
+	ip := net.ParseIP(clientIP(r))
+	if ip == nil || !ip.IsLoopback() {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(CaptureSecret())) == 1
+}
+
+// writeRetryAfter answers 429 with a Retry-After of at least one second.
+func writeRetryAfter(w http.ResponseWriter, wait time.Duration, msg string) {
+	secs := max(int(math.Ceil(wait.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeError(w, http.StatusTooManyRequests, msg)
+}
+
+// authGuard locks an IP out after repeated failed logins. Each lockout of
+// the same IP doubles, up to max; a successful login or a quiet period of
+// max forgets the IP.
+type authGuard struct {
+	maxFailures int
+	base, max   time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*authEntry
+	lastPrune time.Time
+}
+
+type authEntry struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+func newAuthGuard(c config.RateLimitConfig) *authGuard {
+	g := &authGuard{
+		maxFailures: c.MaxAuthFailures,
+		base:        time.Duration(c.LockoutSeconds) * time.Second,
+		max:         time.Duration(c.MaxLockoutSeconds) * time.Second,
+		entries:     make(map[string]*authEntry),
+	}
+	if g.maxFailures <= 0 {
+		g.maxFailures = defaultMaxAuthFailures
+	}
+	if g.base <= 0 {
+		g.base = defaultLockout
+	}
+	if g.max <= 0 {
+		g.max = defaultMaxLockout
+	}
+	g.max = max(g.max, g.base)
+	return g
+}
+
+// locked returns how long ip is still locked out (0 if it is not).
+func (g *authGuard) locked(ip string, now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if e, ok := g.entries[ip]; ok && now.Before(e.lockedUntil) {
+		return e.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// fail records a failed login and returns the lockout it started, if any.
+func (g *authGuard) fail(ip string, now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.pruneLocked(now)
+	e, ok := g.entries[ip]
+	if !ok || now.Sub(e.lastFailure) > g.max {
+		e = &authEntry{}
+		g.entries[ip] = e
+	}
+	e.failures++
+	e.lastFailure = now
+	if e.failures < g.maxFailures {
+		return 0
+	}
+
+	lockout := g.base << min(e.lockouts, 20)
+	if lockout <= 0 || lockout > g.max {
+		lockout = g.max
+	}
+	e.lockouts++
+	e.failures = 0
+	e.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// succeed forgets ip after a successful login.
+func (g *authGuard) succeed(ip string) {
+	g.mu.Lock()
+	delete(g.entries, ip)
+	g.mu.Unlock()
+}
+
+func (g *authGuard) pruneLocked(now time.Time) {
+	if now.Sub(g.lastPrune) < rateStatePrune {
+		return
+	}
+	g.lastPrune = now
+	for ip, e := range g.entries {
+		if now.After(e.lockedUntil) && now.Sub(e.lastFailure) > g.max {
+			delete(g.entries, ip)
+		}
+	}
+}
+
+// rateLimiter is a per-IP token bucket.
+type rateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(c config.RateLimitConfig) *rateLimiter {
+	perMinute, burst := c.RequestsPerMinute, c.Burst
+	if perMinute <= 0 {
+		perMinute = defaultRequestsPerMinute
+	}
+	if burst <= 0 {
+		burst = defaultBurst
+	}
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token for ip. When the bucket is empty it returns false
+// and the time until the next token.
+func (l *rateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pruneLocked(now)
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// pruneLocked drops buckets that have refilled completely.
+func (l *rateLimiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < rateStatePrune {
+		return
+	}
+	l.lastPrune = now
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for ip, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// checkLockout answers 429 and returns false while r's IP is locked out
+// after failed logins. The capture is never locked out.
+func (s *Server) checkLockout(w http.ResponseWriter, r *http.Request) bool {
+	if s.authGuard == nil || isCapture(r) {
+		return true
+	}
+	if wait := s.authGuard.locked(clientIP(r), time.Now()); wait > 0 {
+		writeRetryAfter(w, wait, "too many failed logins; try again later")
+		return false
+	}
+	return true
+}
+
+// authFailed records a failed login (bad password, API token, metrics
+// bearer or export token) from r's IP.
+func (s *Server) authFailed(r *http.Request) {
+	if s.authGuard == nil || isCapture(r) {
+		return
+	}
+	ip := clientIP(r)
+	if lockout := s.authGuard.fail(ip, time.Now()); lockout > 0 {
+		appLog.Info("too many failed logins; locking out client", "remote", ip, "lockout", lockout.String())
+	}
+}
+
+// authSucceeded clears the failure count of r's IP.
+func (s *Server) authSucceeded(r *http.Request) {
+	if s.authGuard == nil || isCapture(r) {
+		return
+	}
+	s.authGuard.succeed(clientIP(r))
+}
+
+// limited wraps an expensive handler with the per-IP token bucket.
+// The capture's requests are not limited.
+func (s *Server) limited(next http.HandlerFunc) http.HandlerFunc {
+	if s.rateLimiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isCapture(r) {
+			if ok, wait := s.rateLimiter.allow(clientIP(r), time.Now()); !ok {
+				appLog.Debug("rate limited", "remote", clientIP(r), "path", r.URL.Path)
+				writeRetryAfter(w, wait, "rate limit exceeded")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"epdcal/internal/config"
+)
+
+func TestRateLimitExemptsOnlyCapture(t *testing.T) {
+	s := &Server{rateLimiter: newRateLimiter(config.RateLimitConfig{RequestsPerMinute: 1, Burst: 1})}
+	h := s.limited(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	do := func(remote, secret string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+		req.RemoteAddr = remote
+		if secret != "" {
+			req.Header.Set(CaptureHeader, secret)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	for _, tt := range []struct {
+		name, remote, secret string
+		limited              bool
+	}{
+		// A reverse proxy on the same host: loopback alone is not enough.
+		{"loopback proxy", "127.0.0.1:40000", "", true},
+		{"loopback with wrong secret", "[::1]:40000", "guess", true},
+		{"remote with the secret", "192.0.2.10:40000", CaptureSecret(), true},
+		{"capture", "127.0.0.1:40001", CaptureSecret(), false},
+	} {
+		first, second := do(tt.remote, tt.secret), do(tt.remote, tt.secret)
+		if first != http.StatusOK {
+			t.Errorf("%s: first request got %d", tt.name, first)
+		}
+		if got := second == http.StatusTooManyRequests; got != tt.limited {
+			t.Errorf("%s: second request got %d, limited = %v, want %v", tt.name, second, got, tt.limited)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventsCacheSize bounds the number of distinct /api/events queries kept.
+const eventsCacheSize = 64
+
+// eventsCacheTTL bounds how long a response for a now-relative window is
+// reused before the window is recomputed.
+const eventsCacheTTL = 30 * time.Second
+
+// cachedResponse is a fully rendered response body plus its validators.
+type cachedResponse struct {
+	body         []byte
+	etag         string
+	lastModified time.Time
+	expires      time.Time
+}
+
+// responseCache is a small LRU of rendered responses keyed by a normalized
+// request key. It is safe for concurrent use.
+type responseCache struct {
+	mu      sync.Mutex
+	max     int
+	ttl     time.Duration
+	order   *list.List // front = most recently used; values are *cacheEntry
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	resp *cachedResponse
+}
+
+func newResponseCache(max int, ttl time.Duration) *responseCache {
+	return &responseCache{
+		max:     max,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, max),
+	}
+}
+
+// get returns the cached response for key if present and not expired.
+func (c *responseCache) get(key string, now time.Time) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if now.After(e.resp.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.resp, true
+}
+
+// put stores body under key, evicting the least recently used entry if the
+// cache is full, and returns the stored response.
+func (c *responseCache) put(key string, body []byte, lastModified, now time.Time) *cachedResponse {
+	sum := sha256.Sum256(body)
+	resp := &cachedResponse{
+		body:         body,
+		etag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		lastModified: lastModified,
+		expires:      now.Add(c.ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*cacheEntry).resp = resp
+		c.order.MoveToFront(el)
+		return resp
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, resp: resp})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return resp
+}
+
+// clear drops every cached response.
+func (c *responseCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
+
+// writeCachedJSON writes resp with ETag / Last-Modified validators and
+// answers 304 Not Modified when the request's If-None-Match (or, without
+// it, If-Modified-Since) shows the client already has this version.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, resp *cachedResponse) {
+	writeCached(w, r, resp, "application/json; charset=utf-8")
+}
+
+// writeCached is writeCachedJSON for an arbitrary content type.
+func writeCached(w http.ResponseWriter, r *http.Request, resp *cachedResponse, contentType string) {
+	h := w.Header()
+	h.Set("ETag", resp.etag)
+	if !resp.lastModified.IsZero() {
+		h.Set("Last-Modified", resp.lastModified.UTC().Format(http.TimeFormat))
+	}
+	// Always revalidate: the data changes with every refresh cycle.
+	h.Set("Cache-Control", "no-cache")
+
+	if notModified(r, resp) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(resp.body)
+}
+
+func notModified(r *http.Request, resp *cachedResponse) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "W/"))
+			if tag == "*" || tag == resp.etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !resp.lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !resp.lastModified.Truncate(time.Second).After(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	appLog "epdcal/internal/log"
+)
+
+const (
+	// streamBuffer is the per-client event buffer. A client that falls this
+	// far behind is disconnected and reconnects via EventSource.
+	streamBuffer = 32
+
+	// streamKeepAlive is the interval of SSE comment lines that keep proxies
+	// and idle timeouts from closing a quiet stream.
+	streamKeepAlive = 25 * time.Second
+
+	// streamRetryMs is the reconnect delay suggested to EventSource.
+	streamRetryMs = 3000
+
+	// batteryPollInterval drives battery_updated events while the UI is not
+	// asking for /api/battery itself.
+	batteryPollInterval = time.Minute
+)
+
+// handleStream serves GET /api/stream as Server-Sent Events.
+//
+// Each event has an `id` (bus sequence), an `event` type (cycle_started,
+// source_fetched, cycle_completed, render_completed, display_completed,
+// config_changed, battery_updated) and a JSON `data` payload. A `hello`
+// event with the current store generation is sent on connect so that a
+// reconnecting client can tell whether it missed a cycle.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The server-wide WriteTimeout would cut the stream after 30 seconds.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	sub := s.bus.Subscribe(streamBuffer)
+	defer sub.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream; charset=utf-8")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var generation uint64
+	if snap := s.store.Current(); snap != nil {
+		generation = snap.Generation
+	}
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetryMs)
+	if err := writeSSE(w, 0, "hello", map[string]any{"generation": generation}); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					appLog.Info("SSE client too slow; disconnected", "remote", r.RemoteAddr)
+				}
+				return
+			}
+			if err := writeSSE(w, ev.ID, ev.Type, ev.Data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE writes a single event frame. id 0 omits the id line.
+func writeSSE(w http.ResponseWriter, id uint64, typ string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		appLog.Error("failed to marshal SSE event", err, "type", typ)
+		payload = []byte("null")
+	}
+	if id > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ, payload)
+	return err
+}
+
+// pollBattery reads the battery periodically so that battery_updated is
+// published even when no client polls /api/battery. readBattery only
+// publishes when the reading changed.
+func (s *Server) pollBattery(ctx context.Context) {
+	t := time.NewTicker(batteryPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if s.bus.Subscribers() == 0 {
+				continue
+			}
+			if _, err := s.readBattery(ctx); err != nil {
+				appLog.Debug("battery poll failed", "error", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"epdcal/internal/model"
+)
+
+// tasksResponse is the JSON response shape for /api/tasks.
+type tasksResponse struct {
+	Tasks           []taskDTO `json:"tasks"`
+	Now             time.Time `json:"now"`
+	DisplayTimeZone string    `json:"display_timezone"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// taskDTO is a JSON-friendly view of tasks.
+type taskDTO struct {
+	SourceID        string     `json:"source_id"`
+	UID             string     `json:"uid"`
+	InstanceKey     string     `json:"instance_key,omitempty"`
+	Summary         string     `json:"summary"`
+	Description     string     `json:"description"`
+	Due             *time.Time `json:"due,omitempty"`
+	DueAllDay       bool       `json:"due_all_day"`
+	Priority        int        `json:"priority"`
+	Status          string     `json:"status,omitempty"`
+	PercentComplete int        `json:"percent_complete"`
+	Completed       *time.Time `json:"completed,omitempty"`
+	Overdue         bool       `json:"overdue"`
+}
+
+// handleTasks returns the VTODO items the last refresh cycle collected
+// from the configured ICS sources. It never touches the network.
+//
+// GET /api/tasks?overdue=1&due_within=3&incomplete=1
+//   - overdue:    기한이 지난 미완료 task 만 포함
+//   - due_within: 앞으로 N일 이내에 기한이 있는 task 만 포함
+//   - incomplete: 완료/취소되지 않은 task 만 포함
+//
+// overdue 와 due_within 을 함께 주면 둘 중 하나라도 만족하는 task 를 반환하고,
+// incomplete 는 항상 AND 조건으로 적용된다. 필터가 없으면 모든 task 를 반환한다.
+// 잘못된 값(예: due_within=abc, overdue=maybe)은 무시하지 않고 400 으로 거절한다.
+// 반복 task 는 refresh window(horizon_days, 최소 35일) 안의 인스턴스만 고려한다.
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	snap := s.store.Current()
+	if snap == nil {
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, "tasks not loaded yet")
+		return
+	}
+
+	q := r.URL.Query()
+	overdueOnly, err := parseBoolParam("overdue", q.Get("overdue"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	incompleteOnly, err := parseBoolParam("incomplete", q.Get("incomplete"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dueWithin, err := parsePositiveInt("due_within", q.Get("due_within"), -1, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	loc := resolveLocationOrLocal(s.cfg.Timezone)
+	now := time.Now().In(loc)
+
+	resp := tasksResponse{
+		Tasks:           []taskDTO{},
+		Now:             now,
+		DisplayTimeZone: loc.String(),
+		UpdatedAt:       snap.UpdatedAt,
+	}
+
+	dueLimit := now.AddDate(0, 0, dueWithin)
+	for _, t := range snap.Tasks {
+		if incompleteOnly && t.IsComplete() {
+			continue
+		}
+		overdue := t.IsOverdue(now)
+		if overdueOnly || dueWithin >= 0 {
+			dueSoon := dueWithin >= 0 && taskDueBetween(t, now, dueLimit)
+			if !(overdueOnly && overdue) && !dueSoon {
+				continue
+			}
+		}
+		resp.Tasks = append(resp.Tasks, toTaskDTO(t, overdue))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// taskDueBetween reports whether t is due within [now, limit]. An all-day
+// task is due for its whole day, so one due today still counts.
+func taskDueBetween(t model.Task, now, limit time.Time) bool {
+	if t.Due.IsZero() {
+		return false
+	}
+	from := now
+	if t.DueAllDay {
+		from = startOfDay(now)
+	}
+	return !t.Due.Before(from) && !t.Due.After(limit)
+}
+
+func toTaskDTO(t model.Task, overdue bool) taskDTO {
+	dto := taskDTO{
+		SourceID:        t.SourceID,
+		UID:             t.UID,
+		InstanceKey:     t.InstanceKey,
+		Summary:         t.Summary,
+		Description:     t.Description,
+		DueAllDay:       t.DueAllDay,
+		Priority:        t.Priority,
+		Status:          t.Status,
+		PercentComplete: t.PercentComplete,
+		Overdue:         overdue,
+	}
+	if !t.Due.IsZero() {
+		due := t.Due
+		dto.Due = &due
+	}
+	if !t.Completed.IsZero() {
+		completed := t.Completed
+		dto.Completed = &completed
+	}
+	return dto
+}
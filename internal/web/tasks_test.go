@@ -0,0 +1,58 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"epdcal/internal/config"
+	"epdcal/internal/model"
+)
+
+func TestTaskDueBetween(t *testing.T) {
+	loc := time.FixedZone("KST", 9*3600)
+	now := time.Date(2025, 1, 20, 15, 0, 0, 0, loc)
+	limit := now.AddDate(0, 0, 3)
+
+	tests := []struct {
+		name string
+		task model.Task
+		want bool
+	}{
+		{"all-day due today", model.Task{Due: time.Date(2025, 1, 20, 0, 0, 0, 0, loc), DueAllDay: true}, true},
+		{"all-day due yesterday", model.Task{Due: time.Date(2025, 1, 19, 0, 0, 0, 0, loc), DueAllDay: true}, false},
+		{"timed due earlier today", model.Task{Due: time.Date(2025, 1, 20, 9, 0, 0, 0, loc)}, false},
+		{"timed due later today", model.Task{Due: time.Date(2025, 1, 20, 18, 0, 0, 0, loc)}, true},
+		{"beyond limit", model.Task{Due: time.Date(2025, 1, 24, 0, 0, 0, 0, loc), DueAllDay: true}, false},
+		{"no due date", model.Task{}, false},
+	}
+	for _, tt := range tests {
+		if got := taskDueBetween(tt.task, now, limit); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTasksRejectsInvalidFilters(t *testing.T) {
+	s := newEventsTestServer(t, &config.Config{Timezone: "UTC"}, time.Now())
+
+	for _, tt := range []struct {
+		query string
+		code  int
+	}{
+		{"", http.StatusOK},
+		{"overdue=1&incomplete=true&due_within=0", http.StatusOK},
+		{"due_within=3", http.StatusOK},
+		{"due_within=abc", http.StatusBadRequest},
+		{"due_within=-1", http.StatusBadRequest},
+		{"overdue=maybe", http.StatusBadRequest},
+		{"incomplete=yes", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		s.handleTasks(rec, httptest.NewRequest(http.MethodGet, "/api/tasks?"+tt.query, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%q: status %d, want %d", tt.query, rec.Code, tt.code)
+		}
+	}
+}
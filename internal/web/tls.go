@@ -0,0 +1,272 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	appLog "epdcal/internal/log"
+)
+
+const (
+	// selfSignedValidity is the lifetime of a generated certificate; it is
+	// regenerated selfSignedRenewBefore its expiry.
+	selfSignedValidity    = 2 * 365 * 24 * time.Hour
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+
+	defaultCaptureListen = "127.0.0.1:0"
+)
+
+// tlsDir returns where the self-signed certificate is kept: prod vs debug.
+func (s *Server) tlsDir() string {
+	if s.debug {
+		return "./cache/tls"
+	}
+	return "/var/lib/epdcal/tls"
+}
+
+// tlsConfig builds the HTTPS configuration from cfg.TLS: the configured
+// cert/key pair, or a self-signed certificate generated on first use.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	certFile, keyFile := s.cfg.TLS.CertFile, s.cfg.TLS.KeyFile
+	switch {
+	case certFile == "" && keyFile == "":
+		dir := s.tlsDir()
+		certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		if err := ensureSelfSigned(certFile, keyFile, selfSignedHosts(s.cfg.TLS.Hosts)); err != nil {
+			return nil, fmt.Errorf("self-signed certificate: %w", err)
+		}
+	case certFile == "" || keyFile == "":
+		return nil, errors.New("tls.cert_file and tls.key_file must be set together")
+	}
+
+	loader := &certLoader{certFile: certFile, keyFile: keyFile}
+	if _, err := loader.get(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return loader.get()
+		},
+	}, nil
+}
+
+// certLoader serves a cert/key pair and reloads it when either file
+// changes, so renewed certificates (certbot etc.) apply without a restart.
+type certLoader struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+	lastStat time.Time
+}
+
+func (l *certLoader) get() (*tls.Certificate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Handshakes are frequent; look at the files at most every 10s.
+	now := time.Now()
+	if l.cert != nil && now.Sub(l.lastStat) < 10*time.Second {
+		return l.cert, nil
+	}
+	l.lastStat = now
+
+	cfi, cerr := os.Stat(l.certFile)
+	kfi, kerr := os.Stat(l.keyFile)
+	if cerr != nil || kerr != nil {
+		if l.cert != nil {
+			return l.cert, nil
+		}
+		return nil, errors.Join(cerr, kerr)
+	}
+	if l.cert != nil && cfi.ModTime().Equal(l.certMod) && kfi.ModTime().Equal(l.keyMod) {
+		return l.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		if l.cert != nil {
+			// Keep serving the old pair while a renewal is half written.
+			appLog.Error("failed to reload TLS certificate; keeping the previous one", err, "cert_file", l.certFile)
+			return l.cert, nil
+		}
+		return nil, err
+	}
+	if l.cert != nil {
+		appLog.Info("TLS certificate reloaded", "cert_file", l.certFile)
+	}
+	l.cert, l.certMod, l.keyMod = &cert, cfi.ModTime(), kfi.ModTime()
+	return l.cert, nil
+}
+
+// selfSignedHosts returns the names a self-signed certificate should
+// cover: localhost, the hostname (and its mDNS .local name), every local
+// interface address and the configured extra hosts.
+func selfSignedHosts(extra []string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name)
+		if !strings.Contains(name, ".") {
+			hosts = append(hosts, name+".local")
+		}
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLinkLocalUnicast() {
+				hosts = append(hosts, ipnet.IP.String())
+			}
+		}
+	}
+	for _, h := range extra {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	slices.Sort(hosts)
+	return slices.Compact(hosts)
+}
+
+// ensureSelfSigned reuses the certificate at certFile if it is still
+// valid for a while and covers hosts; otherwise it generates a new ECDSA
+// P-256 self-signed certificate and key (0600).
+func ensureSelfSigned(certFile, keyFile string, hosts []string) error {
+	if ok, reason := selfSignedUsable(certFile, keyFile, hosts); ok {
+		return nil
+	} else if reason != "" {
+		appLog.Info("regenerating self-signed TLS certificate", "reason", reason)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0o700); err != nil {
+		return err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"epdcal"}, CommonName: "epdcal self-signed"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return err
+	}
+	appLog.Info("generated self-signed TLS certificate",
+		"cert_file", certFile,
+		"hosts", strings.Join(hosts, ","),
+		"expires", tmpl.NotAfter.Format(time.DateOnly),
+	)
+	return nil
+}
+
+// selfSignedUsable reports whether the existing pair can be reused, and
+// if not (and it exists) why.
+func selfSignedUsable(certFile, keyFile string, hosts []string) (bool, string) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, ""
+		}
+		return false, err.Error()
+	}
+	leaf := pair.Leaf
+	if leaf == nil {
+		if leaf, err = x509.ParseCertificate(pair.Certificate[0]); err != nil {
+			return false, err.Error()
+		}
+	}
+	if time.Until(leaf.NotAfter) < selfSignedRenewBefore {
+		return false, "expires " + leaf.NotAfter.Format(time.DateOnly)
+	}
+	for _, h := range hosts {
+		if err := leaf.VerifyHostname(h); err != nil {
+			return false, "does not cover " + h
+		}
+	}
+	return true, ""
+}
+
+// redirectHandler sends every request to the same host and path on the
+// HTTPS port.
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 literal
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host += ":" + httpsPort
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// listenCapture binds the loopback-only plain HTTP listener used by the
+// chromium capture while TLS is on. Binding anything but loopback would
+// serve the UI in clear text, so that is refused.
+func listenCapture(addr string) (net.Listener, error) {
+	if addr == "" {
+		addr = defaultCaptureListen
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("tls.capture_listen: %w", err)
+	}
+	ip := net.ParseIP(host)
+	if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("tls.capture_listen %q must be a loopback address", addr)
+	}
+	return net.Listen("tcp", addr)
+}
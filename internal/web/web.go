@@ -3,20 +3,30 @@ package web
 import (
 	"context"
 	"crypto/subtle"
+	"crypto/tls"
 	"embed"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"epdcal/internal/apitoken"
 	"epdcal/internal/battery"
 	"epdcal/internal/config"
+	"epdcal/internal/eventbus"
 	"epdcal/internal/ics"
+	"epdcal/internal/layout"
 	appLog "epdcal/internal/log"
+	"epdcal/internal/metrics"
+	"epdcal/internal/model"
+	"epdcal/internal/store"
 )
 
 // Server provides HTTP APIs for configuration and schedule access.
@@ -26,15 +36,36 @@ type Server struct {
 	debug bool
 	mux   *http.ServeMux
 
-	// In-memory cache for /api/events responses to avoid redundant
-	// fetch/parse/expand work on every HTTP request.
-	eventsMu    sync.RWMutex
-	eventsCache *eventsCache
+	// store holds the occurrences published by the refresh loop.
+	// /api/events only reads from it and never touches the network.
+	store *store.Store
+
+	// bus carries pipeline notifications to /api/stream subscribers.
+	bus *eventbus.Bus
+
+	// eventsCache holds rendered /api/events bodies keyed by the normalized
+	// query and store generation.
+	eventsCache *responseCache
 
 	// In-memory cache for battery status. This avoids hitting I2C (or
 	// even the mock) on every single HTTP call.
 	batteryMu    sync.RWMutex
 	batteryCache *batteryCache
+
+	// actions are the pipeline operations behind the admin endpoints.
+	actions *Actions
+	// baseCtx outlives requests so that jobs started through the admin API
+	// keep running after the response; it is cancelled on shutdown.
+	baseCtx context.Context
+	// jobRunning guards against overlapping /api/refresh and /api/render.
+	jobRunning atomic.Bool
+
+	// tokens holds the API tokens managed with `epdcal token`.
+	tokens *apitoken.Store
+
+	// authGuard and rateLimiter are nil when rate_limit.disable is set.
+	authGuard   *authGuard
+	rateLimiter *rateLimiter
 }
 
 // embeddedStatic contains the exported Next.js static build.
@@ -46,11 +77,22 @@ type Server struct {
 var embeddedStatic embed.FS
 
 // NewServer constructs a new Server.
-func NewServer(cfg *config.Config, debug bool) *Server {
+func NewServer(cfg *config.Config, debug bool, st *store.Store, bus *eventbus.Bus, actions *Actions) *Server {
 	s := &Server{
-		cfg:   cfg,
-		debug: debug,
-		mux:   http.NewServeMux(),
+		cfg:     cfg,
+		debug:   debug,
+		mux:     http.NewServeMux(),
+		store:   st,
+		bus:     bus,
+		actions: actions,
+		baseCtx: context.Background(),
+		tokens:  apitoken.Open(apitoken.DefaultPath(debug)),
+
+		eventsCache: newResponseCache(eventsCacheSize, eventsCacheTTL),
+	}
+	if !cfg.RateLimit.Disable {
+		s.authGuard = newAuthGuard(cfg.RateLimit)
+		s.rateLimiter = newRateLimiter(cfg.RateLimit)
 	}
 	s.registerRoutes()
 	return s
@@ -58,82 +100,200 @@ func NewServer(cfg *config.Config, debug bool) *Server {
 
 // Handler returns the underlying http.Handler for this server.
 func (s *Server) Handler() http.Handler {
-	h := http.Handler(s.mux)
-	if s.basicAuthEnabled() {
-		appLog.Info("HTTP basic auth enabled", "listen", "http://"+s.cfg.Listen)
-		return s.basicAuthMiddleware(h)
+	if s.authEnabled() {
+		appLog.Info("HTTP basic auth enabled", "listen", s.cfg.Listen, "tls", s.cfg.TLS.Enable, "accounts", len(s.cfg.Accounts()))
 	}
-	return h
+	return s.authMiddleware(s.mux)
 }
 
-// basicAuthEnabled reports whether HTTP Basic Auth is configured.
-func (s *Server) basicAuthEnabled() bool {
-	if s.cfg == nil || s.cfg.BasicAuth == nil {
-		return false
-	}
-	// 빈 사용자명 또는 비밀번호가 설정된 경우에는 비활성화로 취급한다.
-	if s.cfg.BasicAuth.Username == "" || s.cfg.BasicAuth.Password == "" {
+// secureCompare compares two strings in constant time.
+func secureCompare(a, b string) bool {
+	if len(a) != len(b) {
 		return false
 	}
-	return true
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
 }
 
-// basicAuthMiddleware wraps all handlers except /health with HTTP Basic Auth.
-func (s *Server) basicAuthMiddleware(next http.Handler) http.Handler {
-	username := s.cfg.BasicAuth.Username
-	password := s.cfg.BasicAuth.Password
+// HTTP server timeouts. Every handler answers from the store snapshot, the
+// response cache or disk; /api/refresh and /api/render only start a job and
+// return 202, and /api/stream clears its own deadline. WriteTimeout therefore
+// only has to cover sending the largest bodies (preview.png, /calendar.ics,
+// a wide /api/events window) to a slow client over the Pi's Wi-Fi.
+const (
+	serverReadHeaderTimeout = 5 * time.Second
+	serverReadTimeout       = 15 * time.Second
+	serverWriteTimeout      = 30 * time.Second
+	serverIdleTimeout       = 120 * time.Second
+
+	// ShutdownDrainTimeout bounds how long in-flight requests (including
+	// the capture's /calendar load) may take to finish after ctx is
+	// cancelled before remaining connections are closed.
+	ShutdownDrainTimeout = 10 * time.Second
+)
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// /health 는 항상 무인증으로 노출한다.
-		if r.URL.Path == "/health" {
-			next.ServeHTTP(w, r)
-			return
+// StartServer binds cfg.Listen and serves API + 정적 파일 until ctx is
+// cancelled, then shuts down gracefully: the listeners are closed at once
+// and in-flight requests get ShutdownDrainTimeout to complete.
+//
+// With tls.enable, cfg.Listen serves HTTPS. A loopback-only plain HTTP
+// listener (tls.capture_listen) then serves the same handler for the
+// chromium capture, and tls.redirect_listen optionally redirects plain
+// HTTP to HTTPS.
+//
+// ready, if non-nil, receives the base URL the capture should load (e.g.
+// "http://127.0.0.1:8080") once every listener is bound, so that callers
+// can wait for it before pointing the capture at the server. StartServer
+// returns nil after a clean shutdown and an error if binding or serving
+// fails. actions backs the admin endpoints; jobs they start run under ctx.
+func StartServer(ctx context.Context, cfg *config.Config, debug bool, st *store.Store, bus *eventbus.Bus, actions *Actions, ready chan<- string) error {
+	s := NewServer(cfg, debug, st, bus, actions)
+	s.baseCtx = ctx
+
+	handler := s.Handler()
+	newServer := func(h http.Handler) *http.Server {
+		srv := &http.Server{
+			Handler:           h,
+			ReadHeaderTimeout: serverReadHeaderTimeout,
+			ReadTimeout:       serverReadTimeout,
+			WriteTimeout:      serverWriteTimeout,
+			IdleTimeout:       serverIdleTimeout,
+		}
+		// Shutdown waits for active connections to go idle, which an SSE
+		// response never does on its own; end the streams first.
+		srv.RegisterOnShutdown(bus.Shutdown)
+		return srv
+	}
+
+	srv := newServer(handler)
+	scheme := "http"
+	captureURL := "http://" + cfg.Listen
+
+	// extra are the capture and redirect servers that run next to srv.
+	type extraServer struct {
+		name string
+		srv  *http.Server
+		ln   net.Listener
+	}
+	var extra []extraServer
+	closeAll := func() {
+		for _, e := range extra {
+			_ = e.ln.Close()
 		}
+	}
 
-		u, p, ok := r.BasicAuth()
-		if !ok || !secureCompare(u, username) || !secureCompare(p, password) {
-			w.Header().Set("WWW-Authenticate", `Basic realm="EPDCal", charset="UTF-8"`)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+	if cfg.TLS.Enable {
+		tlsCfg, err := s.tlsConfig()
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
 		}
-		next.ServeHTTP(w, r)
-	})
-}
+		srv.TLSConfig = tlsCfg
+		scheme = "https"
 
-// secureCompare compares two strings in constant time.
-func secureCompare(a, b string) bool {
-	if len(a) != len(b) {
-		return false
+		captureLn, err := listenCapture(cfg.TLS.CaptureListen)
+		if err != nil {
+			return err
+		}
+		extra = append(extra, extraServer{name: "capture", srv: newServer(handler), ln: captureLn})
+		captureURL = "http://" + captureLn.Addr().String()
+
+		if cfg.TLS.RedirectListen != "" {
+			_, port, _ := net.SplitHostPort(cfg.Listen)
+			redirectLn, err := net.Listen("tcp", cfg.TLS.RedirectListen)
+			if err != nil {
+				closeAll()
+				return fmt.Errorf("tls.redirect_listen: %w", err)
+			}
+			extra = append(extra, extraServer{name: "redirect", srv: newServer(redirectHandler(port)), ln: redirectLn})
+		}
 	}
-	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
-}
 
-// StartServer starts an HTTP server bound to cfg.Listen and serves
-// API + (추후) 정적 파일. ctx 가 cancel 되면 graceful shutdown 할 수 있도록
-// Shutdown 로직은 main 쪽에서 http.Server 래핑 시 구현하는 것을 권장한다.
-// 이 함수는 API 핸들러 구현에 포커스하기 위해 간단한 ListenAndServe 만 제공한다.
-func StartServer(_ context.Context, cfg *config.Config, debug bool) error {
-	s := NewServer(cfg, debug)
-	appLog.Info("starting HTTP server", "listen", "http://"+cfg.Listen, "debug", debug)
-	return http.ListenAndServe(cfg.Listen, s.Handler())
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		closeAll()
+		return err
+	}
+	if srv.TLSConfig != nil {
+		ln = tls.NewListener(ln, srv.TLSConfig)
+	}
+
+	go s.pollBattery(ctx)
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+
+		appLog.Info("shutting down HTTP server", "drain_timeout", ShutdownDrainTimeout.String())
+		drainCtx, cancel := context.WithTimeout(context.Background(), ShutdownDrainTimeout)
+		defer cancel()
+
+		servers := []*http.Server{srv}
+		for _, e := range extra {
+			servers = append(servers, e.srv)
+		}
+		var wg sync.WaitGroup
+		for _, sv := range servers {
+			wg.Go(func() {
+				if err := sv.Shutdown(drainCtx); err != nil {
+					appLog.Error("HTTP server did not drain in time; closing connections", err)
+					_ = sv.Close()
+				}
+			})
+		}
+		wg.Wait()
+	}()
+
+	for _, e := range extra {
+		appLog.Info("starting HTTP listener", "role", e.name, "listen", "http://"+e.ln.Addr().String())
+		go func() {
+			if err := e.srv.Serve(e.ln); !errors.Is(err, http.ErrServerClosed) {
+				appLog.Error("HTTP listener failed", err, "role", e.name)
+			}
+		}()
+	}
+
+	appLog.Info("starting HTTP server", "listen", scheme+"://"+ln.Addr().String(), "debug", debug)
+	if ready != nil {
+		ready <- captureURL
+	}
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-shutdownDone
+	appLog.Info("HTTP server stopped")
+	return nil
 }
 
 func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/health", s.handleHealth)
-	s.mux.HandleFunc("/api/events", s.handleEvents)
+	// Expensive endpoints go through the per-IP rate limiter (limited).
+	s.mux.HandleFunc("/api/events", s.limited(s.handleEvents))
+	s.mux.HandleFunc("/api/tasks", s.limited(s.handleTasks))
+	s.mux.HandleFunc("/api/conflicts", s.limited(s.handleConflicts))
+	s.mux.HandleFunc("GET /api/sources/{id}/diagnostics", requireAdmin(s.limited(s.handleSourceDiagnostics)))
 	s.mux.HandleFunc("/api/battery", s.handleBattery)
+	s.mux.HandleFunc("GET /api/stream", s.handleStream)
 	s.mux.HandleFunc("/app-config.js", s.handleAppConfigJS)
 	s.mux.HandleFunc("/preview.png", s.handlePreview)
+	if !s.cfg.Export.Disable {
+		s.mux.HandleFunc("GET /calendar.ics", s.limited(s.handleExport))
+		s.mux.HandleFunc("GET /export/{token}/calendar.ics", s.limited(s.handleExportToken))
+	}
+	if !s.cfg.Metrics.Disable {
+		s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	}
+
+	// Admin-only operations. Viewers get 403 (see requireAdmin).
+	s.mux.HandleFunc("GET /api/config", requireAdmin(s.handleGetConfig))
+	s.mux.HandleFunc("POST /api/config", requireAdmin(s.handlePostConfig))
+	s.mux.HandleFunc("POST /api/refresh", requireRefresh(s.limited(s.handleRefresh)))
+	s.mux.HandleFunc("POST /api/render", requireRefresh(s.limited(s.handleRender)))
+	s.mux.HandleFunc("POST /api/cache/clear", requireAdmin(s.handleCacheClear))
 
 	// Static Next.js exported UI (embedded via Go 1.16+ embed.FS).
 	// All non-/api/* and non-/preview.png paths fall back to this handler.
 	s.mux.Handle("/", s.staticFileServer())
-
-	// 추후:
-	// - /api/config
-	// - /api/refresh
-	// - /api/render
-	// - 정적 파일 서빙(Next.js export 결과) 등을 여기에 추가 예정.
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
@@ -148,8 +308,28 @@ func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 // the mock reader) on every single HTTP request. Battery status does not
 // need sub-second precision, so a short TTL is sufficient.
 func (s *Server) handleBattery(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	status, err := s.readBattery(r.Context())
+	if err != nil {
+		if errors.Is(err, errBatteryUnavailable) {
+			writeError(w, http.StatusInternalServerError, "battery reader unavailable")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to read battery")
+		return
+	}
 
+	resp := batteryResponse{
+		Percent:   status.Percent,
+		VoltageMv: status.VoltageMv,
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+var errBatteryUnavailable = errors.New("battery reader unavailable")
+
+// readBattery returns the battery status, reading the hardware at most once
+// per batteryCacheTTL. Fresh readings are also exported as metrics.
+func (s *Server) readBattery(ctx context.Context) (battery.Status, error) {
 	const batteryCacheTTL = 30 * time.Second
 	now := time.Now()
 
@@ -158,40 +338,41 @@ func (s *Server) handleBattery(w http.ResponseWriter, r *http.Request) {
 	bc := s.batteryCache
 	s.batteryMu.RUnlock()
 	if bc != nil && now.Sub(bc.updatedAt) < batteryCacheTTL {
-		resp := batteryResponse{
-			Percent:   bc.status.Percent,
-			VoltageMv: bc.status.VoltageMv,
-		}
-		writeJSON(w, http.StatusOK, resp)
-		return
+		return bc.status, nil
 	}
 
 	br := battery.DefaultReader()
 	if br == nil {
-		writeError(w, http.StatusInternalServerError, "battery reader unavailable")
-		return
+		return battery.Status{}, errBatteryUnavailable
 	}
 
 	status, err := br.Read(ctx)
 	if err != nil {
 		appLog.Error("battery read failed", err)
-		writeError(w, http.StatusInternalServerError, "failed to read battery")
-		return
+		return battery.Status{}, err
 	}
 
 	// Update cache.
 	s.batteryMu.Lock()
+	changed := s.batteryCache == nil || s.batteryCache.status != status
 	s.batteryCache = &batteryCache{
 		status:    status,
 		updatedAt: time.Now(),
 	}
 	s.batteryMu.Unlock()
 
-	resp := batteryResponse{
-		Percent:   status.Percent,
-		VoltageMv: status.VoltageMv,
+	if changed {
+		s.bus.Publish(eventbus.BatteryUpdated, batteryResponse{
+			Percent:   status.Percent,
+			VoltageMv: status.VoltageMv,
+		})
 	}
-	writeJSON(w, http.StatusOK, resp)
+
+	metrics.BatteryPercent.With().Set(float64(status.Percent))
+	if status.VoltageMv > 0 {
+		metrics.BatteryVoltage.With().Set(float64(status.VoltageMv) / 1000)
+	}
+	return status, nil
 }
 
 // handleAppConfigJS exposes a tiny runtime config payload for the static Web UI.
@@ -253,6 +434,12 @@ func (s *Server) staticFileServer() http.Handler {
 			return
 		}
 
+		// 설정 페이지는 admin 전용이다. (viewer 는 /calendar 만 본다)
+		if (path == "/config" || strings.HasPrefix(path, "/config/")) && requestRole(r) != config.RoleAdmin {
+			writeError(w, http.StatusForbidden, "admin role required")
+			return
+		}
+
 		// /health, /preview.png 는 ServeMux 에 별도 핸들러가 등록되어 있어
 		// 정상적인 경우 이 핸들러까지 도달하지 않는다.
 		// 그 외 모든 경로는 Next 정적 빌드(embedded UI)로 서빙한다.
@@ -277,18 +464,22 @@ func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
 
 // eventsResponse is the JSON response shape for /api/events.
 type eventsResponse struct {
-	Occurrences     []occurrenceDTO `json:"occurrences"`
-	TruncatedUIDs   []string        `json:"truncated_uids,omitempty"`
-	RangeStart      time.Time       `json:"range_start"`
-	RangeEnd        time.Time       `json:"range_end"`
-	DisplayTimeZone string          `json:"display_timezone"`
-	WeekStart       string          `json:"week_start"`
-}
-
-// eventsCache holds a cached /api/events response and its timestamp.
-type eventsCache struct {
-	resp      eventsResponse
-	updatedAt time.Time
+	Occurrences   []occurrenceDTO `json:"occurrences"`
+	Segments      []segmentDTO    `json:"segments,omitempty"`
+	Days          []dayLayoutDTO  `json:"days,omitempty"`
+	TruncatedUIDs []string        `json:"truncated_uids,omitempty"`
+	RangeStart    time.Time       `json:"range_start"`
+	RangeEnd      time.Time       `json:"range_end"`
+	// DataStart / DataEnd is the part of the requested range the store
+	// covers (the refresh cycle's window); RangeTruncated is set when it is
+	// smaller than RangeStart / RangeEnd.
+	DataStart       time.Time `json:"data_start"`
+	DataEnd         time.Time `json:"data_end"`
+	RangeTruncated  bool      `json:"range_truncated"`
+	DisplayTimeZone string    `json:"display_timezone"`
+	WeekStart       string    `json:"week_start"`
+	Generation      uint64    `json:"generation"` // refresh cycle that produced the data
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // batteryCache holds the last known battery status and its timestamp.
@@ -306,209 +497,365 @@ type batteryResponse struct {
 // occurrenceDTO is a JSON-friendly view of occurrences.
 type occurrenceDTO struct {
 	SourceID    string    `json:"source_id"`
+	SourceIDs   []string  `json:"source_ids,omitempty"`
 	UID         string    `json:"uid"`
 	InstanceKey string    `json:"instance_key"`
 	Summary     string    `json:"summary"`
 	Description string    `json:"description"`
 	Location    string    `json:"location"`
+	Categories  []string  `json:"categories,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	URLDisplay  string    `json:"url_display,omitempty"`
+	Color       string    `json:"color,omitempty"`
+	Priority    int       `json:"priority,omitempty"`
+	HighPrio    bool      `json:"high_priority,omitempty"`
+	HasAlarm    bool      `json:"has_alarm,omitempty"`
+	Transparent bool      `json:"transparent,omitempty"`
+	Conflict    bool      `json:"conflict,omitempty"`
+	AlarmOffset *int      `json:"alarm_offset_minutes,omitempty"`
 	AllDay      bool      `json:"all_day"`
+	AllDayLane  bool      `json:"all_day_lane,omitempty"`
+	Ink         string    `json:"ink,omitempty"`
 	Start       time.Time `json:"start"`
 	End         time.Time `json:"end"`
 }
 
-// handleEvents returns expanded occurrences for the configured ICS sources
-// within a requested time window.
-//
-// GET /api/events?days=7&backfill=1
-//   - days:     앞으로 몇 일을 볼 것인지 (기본 7)
-//   - backfill: 과거 몇 일을 포함할지 (기본 1)
-//
-// 디스플레이 타임존은 config.Timezone 기준이며, 잘못된 Timezone 이면 time.Local 을 사용한다.
-func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	// Parse query parameters.
-	q := r.URL.Query()
-	rawDays := q.Get("days")
-	rawBackfill := q.Get("backfill")
+// segmentDTO is a JSON-friendly view of a per-day segment. Occurrence
+// carries the original (unclipped) span.
+type segmentDTO struct {
+	Day                   string        `json:"day"` // YYYY-MM-DD in the display timezone
+	Start                 time.Time     `json:"start"`
+	End                   time.Time     `json:"end"`
+	ContinuesFromPrevious bool          `json:"continues_from_previous"`
+	ContinuesToNext       bool          `json:"continues_to_next"`
+	DayIndex              int           `json:"day_index"`
+	DayCount              int           `json:"day_count"`
+	Occurrence            occurrenceDTO `json:"occurrence"`
+}
 
-	days := parseIntDefault(rawDays, 7)
-	if days <= 0 {
-		days = 7
+func toSegmentDTOs(segs []model.Segment) []segmentDTO {
+	out := make([]segmentDTO, 0, len(segs))
+	for _, seg := range segs {
+		out = append(out, toSegmentDTO(seg))
 	}
-	backfill := parseIntDefault(rawBackfill, 1)
-	if backfill < 0 {
-		backfill = 0
+	return out
+}
+
+func toSegmentDTO(seg model.Segment) segmentDTO {
+	return segmentDTO{
+		Day:                   seg.Day.Format("2006-01-02"),
+		Start:                 seg.Start,
+		End:                   seg.End,
+		ContinuesFromPrevious: seg.ContinuesFromPrevious,
+		ContinuesToNext:       seg.ContinuesToNext,
+		DayIndex:              seg.DayIndex,
+		DayCount:              seg.DayCount,
+		Occurrence:            toOccurrenceDTO(seg.Occurrence),
 	}
+}
 
-	// Display timezone.
-	loc := resolveLocationOrLocal(s.cfg.Timezone)
+// dayLayoutDTO is a JSON-friendly view of a layout.DayLayout.
+type dayLayoutDTO struct {
+	Day      string         `json:"day"` // YYYY-MM-DD in the display timezone
+	AllDay   []segmentDTO   `json:"all_day"`
+	Timed    []placementDTO `json:"timed"`
+	Overflow *overflowDTO   `json:"overflow,omitempty"`
+}
 
-	// Small in-memory cache for expanded events. This avoids repeating
-	// ICS fetch/parse/expand work on every HTTP request. The cache is
-	// primarily a performance optimization for Web UI access; the main
-	// refresh loop is still driven by cron in cmd/epdcal.
-	const eventsCacheTTL = 30 * time.Second
-	cacheNow := time.Now()
-
-	s.eventsMu.RLock()
-	ec := s.eventsCache
-	s.eventsMu.RUnlock()
-	if ec != nil && cacheNow.Sub(ec.updatedAt) < eventsCacheTTL {
-		writeJSON(w, http.StatusOK, ec.resp)
-		return
-	}
+// placementDTO is a timed segment with its column position.
+type placementDTO struct {
+	segmentDTO
+	Column  int `json:"column"`
+	Columns int `json:"columns"`
+	Stack   int `json:"stack"`
+}
 
-	now := time.Now().In(loc)
+// overflowDTO summarizes the segments hidden by max_events_per_day.
+type overflowDTO struct {
+	Count    int       `json:"count"` // formatted by the client (i18n)
+	Earliest time.Time `json:"earliest"`
+	Hidden   []string  `json:"hidden"` // instance keys of the hidden segments
+}
 
-	var rangeStart, rangeEnd time.Time
-	if rawDays == "" && rawBackfill == "" {
-		// 기본값: 이번 주 시작(week_start 설정에 따라 일/월)을 기준으로 35일 범위.
-		rangeStart = startOfWeek(now, loc, s.cfg.WeekStart)
-		rangeEnd = rangeStart.AddDate(0, 0, 35)
-
-		// 로깅 편의를 위해 days/backfill 를 재계산한 개념값으로 덮어쓴다.
-		// backfill 은 now 기준 과거 일 수, days 는 앞으로의 일 수로 본다.
-		backfill = int(now.Sub(rangeStart).Hours() / 24)
-		if backfill < 0 {
-			backfill = 0
+func toDayLayoutDTOs(days []layout.DayLayout) []dayLayoutDTO {
+	out := make([]dayLayoutDTO, 0, len(days))
+	for _, d := range days {
+		dto := dayLayoutDTO{
+			Day:    d.Day.Format("2006-01-02"),
+			AllDay: toSegmentDTOs(d.AllDay),
+			Timed:  make([]placementDTO, 0, len(d.Timed)),
 		}
-		days = int(rangeEnd.Sub(now).Hours() / 24)
-		if days <= 0 {
-			days = 1
+		for _, p := range d.Timed {
+			dto.Timed = append(dto.Timed, placementDTO{
+				segmentDTO: toSegmentDTO(p.Segment),
+				Column:     p.Column,
+				Columns:    p.Columns,
+				Stack:      p.Stack,
+			})
 		}
-	} else {
-		// 사용자가 days/backfill 을 명시하면 기존 동작 유지: now 기준.
-		rangeStart = now.AddDate(0, 0, -backfill)
-		rangeEnd = now.AddDate(0, 0, days)
+		if d.Overflow != nil {
+			ov := &overflowDTO{
+				Count:    d.Overflow.Count,
+				Earliest: d.Overflow.Earliest,
+				Hidden:   make([]string, 0, len(d.Overflow.Hidden)),
+			}
+			for _, seg := range d.Overflow.Hidden {
+				ov.Hidden = append(ov.Hidden, seg.Occurrence.InstanceKey)
+			}
+			dto.Overflow = ov
+		}
+		out = append(out, dto)
 	}
+	return out
+}
 
-	appLog.Info("api events request",
-		"days", days,
-		"backfill", backfill,
-		"range_start", rangeStart.Format(time.RFC3339),
-		"range_end", rangeEnd.Format(time.RFC3339),
-		"timezone", s.cfg.Timezone,
-	)
+// toOccurrenceDTO converts a model.Occurrence into its JSON view.
+func toOccurrenceDTO(occ model.Occurrence) occurrenceDTO {
+	dto := occurrenceDTO{
+		SourceID:    occ.SourceID,
+		SourceIDs:   occ.SourceIDs,
+		UID:         occ.UID,
+		InstanceKey: occ.InstanceKey,
+		Summary:     occ.Summary,
+		Description: occ.Description,
+		Location:    occ.Location,
+		Categories:  occ.Categories,
+		URL:         occ.URL,
+		URLDisplay:  shortLink(occ.URL, 32),
+		Color:       occ.Color,
+		Priority:    occ.Priority,
+		HighPrio:    occ.IsHighPriority(),
+		HasAlarm:    occ.HasAlarm(),
+		Transparent: occ.Transparent,
+		Conflict:    occ.Conflict,
+		AllDay:      occ.AllDay,
+		AllDayLane:  occ.AllDayLane,
+		Ink:         string(occ.Ink),
+		Start:       occ.Start,
+		End:         occ.End,
+	}
+	if occ.AlarmOffset != nil {
+		m := int(occ.AlarmOffset.Minutes())
+		dto.AlarmOffset = &m
+	}
+	return dto
+}
 
-	// Build ICS sources from config.
-	sources := make([]ics.Source, 0, len(s.cfg.ICS))
-	for _, csrc := range s.cfg.ICS {
-		if csrc.URL == "" {
-			continue
-		}
-		id := csrc.ID
-		if id == "" {
-			if csrc.Name != "" {
-				id = csrc.Name
-			} else {
-				id = csrc.URL
-			}
-		}
-		sources = append(sources, ics.Source{
-			ID:  id,
-			URL: csrc.URL,
-		})
+// shortLink returns a compact display form of a (meeting) URL suitable for
+// the panel: scheme, "www.", query and fragment are dropped and the result
+// is truncated to maxLen runes with an ellipsis.
+//
+//	https://us02web.zoom.us/j/123456789?pwd=abc -> us02web.zoom.us/j/123456789
+func shortLink(raw string, maxLen int) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
 	}
+	out := strings.TrimPrefix(u.Host, "www.") + strings.TrimSuffix(u.EscapedPath(), "/")
+	if r := []rune(out); maxLen > 0 && len(r) > maxLen {
+		out = string(r[:maxLen-1]) + "…"
+	}
+	return out
+}
 
-	if len(sources) == 0 {
-		writeJSON(w, http.StatusOK, eventsResponse{
-			Occurrences:     []occurrenceDTO{},
-			TruncatedUIDs:   nil,
-			RangeStart:      rangeStart,
-			RangeEnd:        rangeEnd,
-			DisplayTimeZone: loc.String(),
-			WeekStart:       s.cfg.WeekStart,
-		})
+// handleEvents returns expanded occurrences for the configured ICS sources
+// within a requested time window.
+//
+// Occurrences come from the store published by the refresh loop; the
+// handler never fetches feeds itself. The response carries the store
+// generation so the capture step can tell which cycle it is rendering.
+// Before the first cycle completes it answers 503 with Retry-After.
+//
+// GET /api/events?start=2025-01-01&end=2025-01-31&source=work&all_day=exclude&q=standup
+//   - start, end: 범위 (YYYY-MM-DD 또는 RFC 3339). 날짜로 준 end 는 그 날을 포함하고,
+//     end 를 생략하면 start 부터 horizon_days 일
+//   - view:     day | week | month | agenda. week_start 기준으로 범위를 계산하며,
+//     start 를 주면 그 날짜를 포함하는 day/week/month (agenda 는 start 부터 horizon_days)
+//   - weeks:    view=week 에서 몇 주를 반환할지 (기본 1, Web UI 의 5주 grid 는 weeks=5)
+//   - days, backfill: now 기준 범위 (기존 방식; days 기본 horizon_days, backfill 기본 1).
+//     start/end/view 와 함께 쓸 수 없다
+//   - source:   소스 ID 필터 (여러 번 지정 가능)
+//   - all_day:  only | exclude
+//   - q:        제목/설명/장소 부분 문자열 검색 (대소문자 무시)
+//   - segments: true 이면 표시 타임존 기준 일자별 segment 목록도 함께 반환
+//     (여러 날에 걸치거나 자정을 넘는 일정을 하루 단위로 나눈 결과)
+//   - layout:   true 이면 일자별 레이아웃(겹치는 일정의 column 배치, stacking
+//     순서, max_events_per_day 초과분 개수)을 days 로 함께 반환
+//   - capacity: layout 의 하루 최대 일정 수 (기본 max_events_per_day, 0 = 무제한)
+//
+// 범위 관련 파라미터가 없으면 이번 주 시작부터 오늘 + horizon_days 까지(주 단위
+// 올림)를 반환한다. 잘못된 값은 기본값으로 대체하지 않고 400 과 함께 이유를 반환한다.
+//
+// store 에는 refresh cycle 의 window 만 있으므로, 실제로 데이터가 있는 구간을
+// data_start/data_end(와 X-Data-Range 헤더)로 알려 주고, 범위가 그 밖이면 416 을 반환한다.
+//
+// 디스플레이 타임존은 config.Timezone 기준이며, 잘못된 Timezone 이면 time.Local 을 사용한다.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	// Display timezone.
+	loc := resolveLocationOrLocal(s.cfg.Timezone)
+	now := time.Now().In(loc)
+
+	eq, err := s.parseEventsQuery(r.URL.Query(), loc, now)
+	if err == nil {
+		err = eq.negotiate(r.Header.Get("Accept"))
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	w.Header().Set("Vary", "Accept")
+	contentType := eventsContentType(eq.format)
 
-	// Choose cache dir: prod vs debug.
-	const defaultCacheDir = "/var/lib/epdcal/ics-cache"
-	cacheDir := defaultCacheDir
-	if s.debug {
-		cacheDir = "./cache/ics-cache"
+	snap := s.store.Current()
+	if snap == nil {
+		// 첫 refresh cycle 이 끝나기 전에는 보여줄 데이터가 없다.
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, "events not loaded yet")
+		return
 	}
 
-	fetcher := ics.NewFetcher(cacheDir)
+	rangeStart, rangeEnd := eq.rangeStart, eq.rangeEnd
 
-	// Fetch ICS feeds.
-	fetchResults, fetchErrs := fetcher.FetchAll(ctx, sources)
-	if len(fetchErrs) > 0 {
-		appLog.Error("api events: one or more ICS fetches failed", errorsAggregate(fetchErrs), "error_count", len(fetchErrs))
+	// The store only holds the refresh cycle's window and nothing is fetched
+	// here: a range outside it is refused, a partly covered one is answered
+	// with what is available and the covered part is reported.
+	dataStart, dataEnd := rangeStart, rangeEnd
+	if !snap.RangeStart.IsZero() && dataStart.Before(snap.RangeStart) {
+		dataStart = snap.RangeStart.In(loc)
+	}
+	if !snap.RangeEnd.IsZero() && dataEnd.After(snap.RangeEnd) {
+		dataEnd = snap.RangeEnd.In(loc)
 	}
+	if !dataEnd.After(dataStart) {
+		writeError(w, http.StatusRequestedRangeNotSatisfiable, fmt.Sprintf(
+			"range is outside the refreshed window %s – %s",
+			snap.RangeStart.In(loc).Format(time.RFC3339), snap.RangeEnd.In(loc).Format(time.RFC3339)))
+		return
+	}
+	truncated := !dataStart.Equal(rangeStart) || !dataEnd.Equal(rangeEnd)
+	w.Header().Set("X-Data-Range", dataStart.Format(time.RFC3339)+"/"+dataEnd.Format(time.RFC3339))
 
-	// Parse all ICS bodies into ParsedEvent list.
-	parsedEvents := make([]ics.ParsedEvent, 0)
-	for _, res := range fetchResults {
-		events, err := ics.ParseICS(res.Source, res.Body)
-		if err != nil {
-			appLog.Error("api events: parse failed for source", err, "id", res.Source.ID)
-			continue
-		}
-		parsedEvents = append(parsedEvents, events...)
+	// Responses are cached per normalized query (window, filters, display
+	// zone, week start) and store generation. Now-relative windows are
+	// keyed by their parameters and bounded by the cache TTL.
+	cacheKey := eq.cacheKey(loc, s.cfg.WeekStart, snap.Generation)
+	if cached, ok := s.eventsCache.get(cacheKey, time.Now()); ok {
+		writeCached(w, r, cached, contentType)
+		return
 	}
 
-	// Expand into occurrences.
-	expandCfg := ics.ExpandConfig{
-		DisplayLocation:        loc,
-		RangeStart:             rangeStart,
-		RangeEnd:               rangeEnd,
-		MaxOccurrencesPerEvent: 5000,
+	appLog.Debug("api events request",
+		"range_start", rangeStart.Format(time.RFC3339),
+		"range_end", rangeEnd.Format(time.RFC3339),
+		"range_truncated", truncated,
+		"generation", snap.Generation,
+	)
+
+	occs := make([]model.Occurrence, 0, len(snap.Occurrences))
+	for _, occ := range snap.Occurrences {
+		if eq.matches(occ) {
+			occs = append(occs, occ)
+		}
 	}
 
-	expandResult, err := ics.ExpandOccurrences(parsedEvents, expandCfg)
-	if err != nil {
-		appLog.Error("api events: expand failed", err)
-		writeError(w, http.StatusInternalServerError, "failed to expand events")
+	if eq.format != formatJSON {
+		opts := ics.ExportOptions{
+			Name:       "epdcal",
+			Location:   loc,
+			RangeStart: rangeStart,
+			RangeEnd:   rangeEnd,
+			Stamp:      snap.UpdatedAt,
+		}
+		var doc any
+		if eq.format == formatJCal {
+			doc = ics.JCal(occs, opts)
+		} else {
+			doc = ics.JSCalendar(occs, opts)
+		}
+		body, err := json.Marshal(doc)
+		if err != nil {
+			appLog.Error("api events: failed to encode response", err, "format", eq.format)
+			writeError(w, http.StatusInternalServerError, "failed to encode events")
+			return
+		}
+		writeCached(w, r, s.eventsCache.put(cacheKey, append(body, '\n'), eq.lastModified(snap.UpdatedAt), time.Now()), contentType)
 		return
 	}
 
-	// Convert to DTO.
-	dtos := make([]occurrenceDTO, 0, len(expandResult.Occurrences))
-	for _, occ := range expandResult.Occurrences {
-		dtos = append(dtos, occurrenceDTO{
-			SourceID:    occ.SourceID,
-			UID:         occ.UID,
-			InstanceKey: occ.InstanceKey,
-			Summary:     occ.Summary,
-			Description: occ.Description,
-			Location:    occ.Location,
-			AllDay:      occ.AllDay,
-			Start:       occ.Start,
-			End:         occ.End,
-		})
+	dtos := make([]occurrenceDTO, 0, len(occs))
+	for _, occ := range occs {
+		dtos = append(dtos, toOccurrenceDTO(occ))
 	}
 
 	resp := eventsResponse{
 		Occurrences:     dtos,
-		TruncatedUIDs:   expandResult.TruncatedEvents,
+		TruncatedUIDs:   snap.TruncatedUIDs,
 		RangeStart:      rangeStart,
 		RangeEnd:        rangeEnd,
+		DataStart:       dataStart,
+		DataEnd:         dataEnd,
+		RangeTruncated:  truncated,
 		DisplayTimeZone: loc.String(),
 		WeekStart:       s.cfg.WeekStart,
+		Generation:      snap.Generation,
+		UpdatedAt:       snap.UpdatedAt,
 	}
-
-	// Update in-memory cache for subsequent requests.
-	s.eventsMu.Lock()
-	s.eventsCache = &eventsCache{
-		resp:      resp,
-		updatedAt: time.Now(),
+	if eq.segments || eq.layout {
+		segs := layout.SplitByDay(occs, loc, rangeStart, rangeEnd)
+		if eq.segments {
+			resp.Segments = toSegmentDTOs(segs)
+		}
+		if eq.layout {
+			resp.Days = toDayLayoutDTOs(layout.LayoutDays(segs, eq.capacity))
+		}
 	}
-	s.eventsMu.Unlock()
 
-	writeJSON(w, http.StatusOK, resp)
+	body, err := json.Marshal(resp)
+	if err != nil {
+		appLog.Error("api events: failed to encode response", err)
+		writeError(w, http.StatusInternalServerError, "failed to encode events")
+		return
+	}
+	writeCachedJSON(w, r, s.eventsCache.put(cacheKey, append(body, '\n'), eq.lastModified(snap.UpdatedAt), time.Now()))
 }
 
-func parseIntDefault(s string, def int) int {
-	if s == "" {
-		return def
+// eventsContentType returns the media type of an /api/events format.
+func eventsContentType(format string) string {
+	switch format {
+	case formatJCal:
+		return ics.JCalContentType + "; charset=utf-8"
+	case formatJSCalendar:
+		return ics.JSCalendarContentType + "; charset=utf-8"
 	}
-	n, err := strconv.Atoi(s)
-	if err != nil {
-		return def
+	return "application/json; charset=utf-8"
+}
+
+// icsSources builds the ICS source list from config. Built-in holiday
+// sources and entries without a URL are skipped; a missing ID falls back to
+// the name or the URL.
+func (s *Server) icsSources() []ics.Source {
+	sources := make([]ics.Source, 0, len(s.cfg.ICS))
+	for _, csrc := range s.cfg.ICS {
+		if csrc.IsHolidays() || csrc.URL == "" {
+			continue
+		}
+		id := csrc.ID
+		if id == "" {
+			if csrc.Name != "" {
+				id = csrc.Name
+			} else {
+				id = csrc.URL
+			}
+		}
+		sources = append(sources, ics.Source{
+			ID:  id,
+			URL: csrc.URL,
+		})
 	}
-	return n
+	return sources
 }
 
 func resolveLocationOrLocal(name string) *time.Location {
@@ -560,19 +907,3 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 	}
 	writeJSON(w, status, errResp{Error: msg})
 }
-
-// errorsAggregate is similar to the helper in cmd/epdcal/main.go.
-// TODO: deduplicate in a shared internal package.
-func errorsAggregate(errs []error) error {
-	if len(errs) == 0 {
-		return nil
-	}
-	var b strings.Builder
-	for i, e := range errs {
-		if i > 0 {
-			b.WriteString("; ")
-		}
-		b.WriteString(e.Error())
-	}
-	return errors.New(b.String())
-}
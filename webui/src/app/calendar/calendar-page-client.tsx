@@ -15,12 +15,38 @@ import { I18nProvider, Locale, useI18n } from "@/app/core/i18n";
 
 type WeekStart = "monday" | "sunday";
 
+// 하루 칸에 표시할 최대 일정 수 (나머지는 "+N" 으로 요약)
+const MAX_EVENTS_PER_CELL = 3;
+
 interface EventsResponse {
   range_start: string;
   range_end: string;
   display_timezone: string;
   week_start?: string;
+  generation?: number;
+  updated_at?: string;
   occurrences?: OccurrenceDTO[];
+  days?: DayLayoutDTO[];
+}
+
+interface SegmentDTO {
+  day: string; // YYYY-MM-DD (display timezone)
+  start: string;
+  end: string;
+  occurrence: OccurrenceDTO;
+}
+
+interface PlacementDTO extends SegmentDTO {
+  column: number;
+  columns: number;
+  stack: number;
+}
+
+interface DayLayoutDTO {
+  day: string;
+  all_day: SegmentDTO[];
+  timed: PlacementDTO[];
+  overflow?: { count: number };
 }
 
 interface OccurrenceDTO {
@@ -31,6 +57,8 @@ interface OccurrenceDTO {
   description: string;
   location: string;
   all_day: boolean;
+  all_day_lane?: boolean;
+  ink?: "black" | "red";
   start: string;
   end: string;
 }
@@ -62,9 +90,13 @@ function CalendarContent() {
   const [eventsByDate, setEventsByDate] = useState<
     Record<string, OccurrenceDTO[]>
   >({});
+  const [overflowByDate, setOverflowByDate] = useState<
+    Record<string, number>
+  >({});
   const [batteryPercent, setBatteryPercent] = useState<number | null>(null);
   const [eventsLoaded, setEventsLoaded] = useState(false);
   const [batteryLoaded, setBatteryLoaded] = useState(false);
+  const [generation, setGeneration] = useState<number | null>(null);
 
   const today = useMemo(() => new Date(), []);
   const now = today; // alias
@@ -75,7 +107,13 @@ function CalendarContent() {
 
     async function load() {
       try {
-        const res = await fetch(window.location.origin + "/api/events");
+        // 하루 칸에 들어갈 일정 수/순서는 서버 레이아웃(layout=1)이 결정하고,
+        // 넘치는 일정은 overflow 로 "+N" 요약만 받는다.
+        // 범위는 아래 5주 grid 와 같다 (이번 주 시작부터 5주).
+        const res = await fetch(
+          window.location.origin +
+            `/api/events?view=week&weeks=5&layout=1&capacity=${MAX_EVENTS_PER_CELL}`,
+        );
         if (!res.ok) {
           throw new Error(`HTTP ${res.status}`);
         }
@@ -90,16 +128,23 @@ function CalendarContent() {
           setDisplayTimezone(data.display_timezone);
         }
 
-        // 날짜별로 occurrence 를 그룹핑
+        // 날짜별로 occurrence 를 그룹핑 (all-day lane 먼저, 이후 stacking 순서)
         const grouped: Record<string, OccurrenceDTO[]> = {};
-        for (const occ of data.occurrences ?? []) {
-          const key = dateKeyFromISO(occ.start);
-          if (!grouped[key]) {
-            grouped[key] = [];
+        const overflow: Record<string, number> = {};
+        for (const day of data.days ?? []) {
+          grouped[day.day] = [
+            ...day.all_day.map((seg) => seg.occurrence),
+            ...[...day.timed]
+              .sort((a, b) => a.stack - b.stack)
+              .map((p) => p.occurrence),
+          ];
+          if (day.overflow && day.overflow.count > 0) {
+            overflow[day.day] = day.overflow.count;
           }
-          grouped[key].push(occ);
         }
         setEventsByDate(grouped);
+        setOverflowByDate(overflow);
+        setGeneration(data.generation ?? null);
 
         // 가장 마지막 업데이트 시각은 클라이언트 기준 fetch 완료 시점으로 사용
         setLastUpdatedAt(new Date());
@@ -173,11 +218,14 @@ function CalendarContent() {
 
   // 캘린더 UI 및 캡처 파이프라인은 /api/events 와 /api/battery 가
   // 모두 성공적으로 로딩된 이후에만 data-ready="true" 로 전환된다.
+  // data-generation 은 /api/events 의 store generation 으로, 캡처는
+  // 방금 끝난 refresh cycle 의 데이터가 그려졌는지 이 값으로 확인한다.
   const ready = eventsLoaded && batteryLoaded;
 
   return (
     <div
       data-ready={ready ? "true" : "false"}
+      data-generation={generation ?? undefined}
       className={`${nanumGothic.className} min-h-screen bg-slate-100 text-slate-900 flex items-center justify-center overflow-auto`}
     >
       <main className="w-[984px] h-[1308px] rounded-xl bg-white shadow-sm px-6 py-6 flex flex-col relative">
@@ -243,6 +291,7 @@ function CalendarContent() {
 
               const dateKey = dateKeyFromDate(day.date);
               const events = eventsByDate[dateKey] ?? [];
+              const overflowCount = overflowByDate[dateKey] ?? 0;
 
               // 색상 규칙:
               // - 이번 달인 평일: 검정
@@ -291,15 +340,23 @@ function CalendarContent() {
                         {t("calendar.no_events")}
                       </p>
                     ) : (
-                      events.slice(0, 3).map((ev, i) => (
+                      events.map((ev, i) => (
                         <p
                           key={i}
-                          className="text-[18px] sm:text-xs text-slate-900 font-semibold truncate"
+                          className={`text-[18px] sm:text-xs font-semibold truncate ${
+                            ev.ink === "red" ? "text-red-600" : "text-slate-900"
+                          }`}
                         >
                           {formatEventLine(ev, locale, t)}
                         </p>
                       ))
                     )}
+                    {overflowCount > 0 && (
+                      <p className="text-[18px] sm:text-xs text-slate-500 font-semibold">
+                        +{overflowCount}
+                        {t("calendar.more_suffix")}
+                      </p>
+                    )}
                   </div>
                 </div>
               );
@@ -420,11 +477,6 @@ function dateKeyFromDate(date: Date): string {
   return `${y}-${String(m).padStart(2, "0")}-${String(d).padStart(2, "0")}`;
 }
 
-function dateKeyFromISO(iso: string): string {
-  const d = new Date(iso);
-  return dateKeyFromDate(d);
-}
-
 function formatEventLine(
   ev: OccurrenceDTO,
   locale: Locale,
@@ -432,8 +484,8 @@ function formatEventLine(
 ): string {
   const title = ev.summary || t("calendar.no_title");
 
-  if (ev.all_day) {
-    // 종일 이벤트: 시간 표시 없이 제목만.
+  if (ev.all_day || ev.all_day_lane) {
+    // 종일 이벤트(또는 rule 로 종일 칸에 옮긴 일정): 시간 표시 없이 제목만.
     return `${t("calendar.all_day_prefix")}${title}`;
   }
 
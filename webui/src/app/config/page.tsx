@@ -90,6 +90,16 @@ function ConfigContent() {
     };
   }, [t]);
 
+  // /api/stream(SSE) 로 렌더 완료를 받아 preview 를 polling 없이 갱신한다.
+  useEffect(() => {
+    if (typeof EventSource === "undefined") return;
+    const es = new EventSource("/api/stream");
+    es.addEventListener("render_completed", () => {
+      setPreviewReloadKey((k) => k + 1);
+    });
+    return () => es.close();
+  }, []);
+
   const handleSave = async () => {
     if (!config) return;
     setSaving(true);
@@ -58,6 +58,7 @@ const ko: Messages = {
   // 캘린더(/calendar)
   "calendar.today": "오늘",
   "calendar.no_events": "일정 없음",
+  "calendar.more_suffix": "개 더",
   "calendar.all_day_prefix": "종일 · ",
   "calendar.last_updated_prefix": "마지막 업데이트:",
   "calendar.error.load": "데이터를 불러오는 중 오류가 발생했습니다.",
@@ -152,6 +153,7 @@ const en: Messages = {
   // Calendar (/calendar)
   "calendar.today": "Today",
   "calendar.no_events": "No events",
+  "calendar.more_suffix": " more",
   "calendar.all_day_prefix": "All-day · ",
   "calendar.last_updated_prefix": "Last updated:",
   "calendar.error.load":